@@ -40,19 +40,19 @@ func validateCreateArgs(cmd *cobra.Command, args []string) error {
 
 func templateConfig() fexcel.FileConfig {
 	return fexcel.FileConfig{
-		Numregs: "Sheet1:A2",
-		Posregs: "Sheet1:D2",
-		Flags:   "Sheet1:G2",
-		Sregs:   "Sheet1:J2",
-		Dins:    "A2",
-		Douts:   "D2",
-		Gins:    "G2",
-		Gouts:   "J2",
-		Rins:    "M2",
-		Routs:   "P2",
-		Ains:    "S2",
-		Aouts:   "V2",
-		Ualms:   "Alarms:A2",
+		Numregs: []string{"Sheet1:A2"},
+		Posregs: []string{"Sheet1:D2"},
+		Flags:   []string{"Sheet1:G2"},
+		Sregs:   []string{"Sheet1:J2"},
+		Dins:    []string{"A2"},
+		Douts:   []string{"D2"},
+		Gins:    []string{"G2"},
+		Gouts:   []string{"J2"},
+		Rins:    []string{"M2"},
+		Routs:   []string{"P2"},
+		Ains:    []string{"S2"},
+		Aouts:   []string{"V2"},
+		Ualms:   []string{"Alarms:A2"},
 		Sheet:   "IO",
 		Offset:  1,
 	}
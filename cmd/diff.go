@@ -10,48 +10,115 @@ import (
 )
 
 var diffCmd = &cobra.Command{
-	Use:     "diff spreadsheet.xlsx target(s)...",
-	Short:   "Compare robot comments to spreadsheet (remote or local)",
-	Example: "  fexcel diff spreadsheet.xlsx 192.168.100.101 192.168.100.102 ./backup/dir ./some/other/backup/dir",
-	Args:    validateDiffArgs,
-	RunE:    diffMain,
+	Use:   "diff spreadsheet.xlsx target(s)...",
+	Short: "Compare robot comments to spreadsheet (remote or local)",
+	Example: `  fexcel diff spreadsheet.xlsx 192.168.100.101 192.168.100.102 ./backup/dir ./some/other/backup/dir
+  fexcel diff --targets-only 192.168.100.101 192.168.100.102 ./backup/dir`,
+	Args: validateDiffArgs,
+	RunE: diffMain,
 }
 
 var (
-	all bool
+	all         bool
+	diffFormat  string
+	targetsOnly bool
+	diffTypes   []string
 )
 
 func init() {
 	diffCmd.Flags().BoolVar(&all, "all", false, "show all comparisons in summary tables instead of just differences")
+	diffCmd.Flags().StringVar(&diffFormat, "format", "table", "output format (table, json, csv or junit)")
+	diffCmd.Flags().BoolVar(&targetsOnly, "targets-only", false, "compare targets to the first target instead of a spreadsheet")
+	diffCmd.Flags().StringSliceVar(&diffTypes, "types", nil, "types to compare with --targets-only, e.g. R,PR,DI (default all)")
+	addToleranceFlag(diffCmd)
 	rootCmd.AddCommand(diffCmd)
 }
 
 func validateDiffArgs(cmd *cobra.Command, args []string) error {
 	if len(args) < 2 {
+		if targetsOnly {
+			return errors.New("requires at least two targets (IP or backup directory)")
+		}
 		return errors.New("requires a spreadsheet and at least one target (IP or backup directory)")
 	}
 
 	// TODO validate args[1:]?
 
+	switch diffFormat {
+	case "table", "json", "csv", "junit":
+	default:
+		return fmt.Errorf("invalid format %q (must be table, json, csv or junit)", diffFormat)
+	}
+
 	return nil
 }
 
 func diffMain(cmd *cobra.Command, args []string) error {
-	fmt.Printf(fexcel.Logo())
-
-	fpath := args[0]
+	if diffFormat == "table" {
+		fmt.Printf(fexcel.Logo())
+	}
 
-	d, err := fexcel.NewDiffCommand(fpath, globalCfg, args[1:]...)
+	var err error
+	globalCfg.Tolerance, err = readTolerance()
 	if err != nil {
 		return err
 	}
 
-	for dataType, _ := range d.Locations() {
-		err := d.FprintTable(os.Stdout, dataType, all)
+	var d *fexcel.DiffCommand
+	if targetsOnly {
+		var types []fexcel.Type
+		for _, s := range diffTypes {
+			t, err := fexcel.ParseType(s)
+			if err != nil {
+				return err
+			}
+			types = append(types, t)
+		}
+
+		d, err = fexcel.NewTargetDiffCommand(globalCfg, types, args...)
+		if err != nil {
+			return err
+		}
+	} else {
+		d, err = fexcel.NewDiffCommand(args[0], globalCfg, args[1:]...)
 		if err != nil {
 			return err
 		}
-		fmt.Fprintln(os.Stdout, "")
+	}
+
+	results, err := d.CompareAll()
+	if err != nil {
+		return err
+	}
+
+	switch diffFormat {
+	case "json":
+		err = d.FprintJSON(os.Stdout, results, all)
+	case "csv":
+		err = d.FprintCSV(os.Stdout, results, all)
+	case "junit":
+		err = d.FprintJUnit(os.Stdout, results)
+	default:
+		for _, r := range results {
+			err = d.FprintTable(os.Stdout, r, all)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stdout, "")
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, r := range results {
+		count += r.Differences()
+	}
+	if count > 0 {
+		// differences are not a usage problem
+		cmd.SilenceUsage = true
+		return fmt.Errorf("found %d %s", count, fexcel.Pluralize("difference", count))
 	}
 
 	return nil
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/onerobotics/fexcel/fexcel"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:     "export spreadsheet.xlsx manifest.yaml",
+	Short:   "Export a spreadsheet's definitions and constants to a JSON or YAML manifest",
+	Example: "  fexcel export spreadsheet.xlsx robot.yaml",
+	Args:    validateExportArgs,
+	RunE:    exportMain,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+}
+
+func validateExportArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("requires a spreadsheet and a manifest path")
+	}
+
+	return nil
+}
+
+func exportMain(cmd *cobra.Command, args []string) error {
+	fmt.Printf(fexcel.Logo())
+
+	fpath, manifestPath := args[0], args[1]
+
+	f, err := fexcel.OpenFile(fpath, globalCfg.FileConfig)
+	if err != nil {
+		return err
+	}
+
+	m, err := f.Manifest()
+	if err != nil {
+		return err
+	}
+
+	for _, w := range f.Warnings {
+		fmt.Println("Warning:", w)
+	}
+
+	err = m.Save(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %s to %s.\n", fpath, manifestPath)
+	return nil
+}
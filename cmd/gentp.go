@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/onerobotics/fexcel/fexcel"
+	"github.com/spf13/cobra"
+)
+
+var genTPCmd = &cobra.Command{
+	Use:     "gen-tp spreadsheet.xlsx -o SETCMTS.ls",
+	Short:   "Generate a TP program that sets a spreadsheet's comments on the controller",
+	Example: "  fexcel gen-tp spreadsheet.xlsx -o SETCMTS.ls",
+	Args:    validateGenTPArgs,
+	RunE:    genTPMain,
+}
+
+var (
+	tpOutput   string
+	tpMaxLines int
+)
+
+func init() {
+	genTPCmd.Flags().StringVarP(&tpOutput, "output", "o", "SETCMTS.ls", "output file")
+	genTPCmd.Flags().IntVar(&tpMaxLines, "max-lines", 500, "split into several programs past this many lines (0 for no limit)")
+	rootCmd.AddCommand(genTPCmd)
+}
+
+func validateGenTPArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("requires a spreadsheet")
+	}
+
+	return nil
+}
+
+func genTPMain(cmd *cobra.Command, args []string) error {
+	fmt.Printf(fexcel.Logo())
+
+	g, err := fexcel.NewTPGenerator(args[0], globalCfg, tpMaxLines)
+	if err != nil {
+		return err
+	}
+
+	// e.g. comments that will be truncated
+	for _, w := range g.Warnings() {
+		fmt.Println("Warning:", w)
+	}
+
+	return g.Generate(os.Stdout, tpOutput)
+}
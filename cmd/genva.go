@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/onerobotics/fexcel/fexcel"
+	"github.com/spf13/cobra"
+)
+
+var genVACmd = &cobra.Command{
+	Use:     "gen-va spreadsheet.xlsx ./output/dir",
+	Short:   "Generate .va and iostate.dg files from a spreadsheet",
+	Example: "  fexcel gen-va spreadsheet.xlsx ./generated",
+	Args:    validateGenVAArgs,
+	RunE:    genVAMain,
+}
+
+func init() {
+	rootCmd.AddCommand(genVACmd)
+}
+
+func validateGenVAArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("requires a spreadsheet and an output directory")
+	}
+
+	return nil
+}
+
+func genVAMain(cmd *cobra.Command, args []string) error {
+	fmt.Printf(fexcel.Logo())
+
+	fpath, dir := args[0], args[1]
+
+	g, err := fexcel.NewGenerator(fpath, globalCfg, dir)
+	if err != nil {
+		return err
+	}
+
+	// e.g. comments that will be truncated
+	for _, w := range g.Warnings() {
+		fmt.Println("Warning:", w)
+	}
+
+	return g.Generate(os.Stdout)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/onerobotics/fexcel/fexcel"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:     "import manifest.yaml spreadsheet.xlsx",
+	Short:   "Create a spreadsheet from a JSON or YAML manifest",
+	Example: "  fexcel import robot.yaml ./doc/spreadsheet.xlsx",
+	Args:    validateImportArgs,
+	RunE:    importMain,
+}
+
+func init() {
+	importCmd.Flags().BoolVar(&headers, "headers", false, "write column header names")
+	rootCmd.AddCommand(importCmd)
+}
+
+func validateImportArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("requires a manifest and a spreadsheet path")
+	}
+
+	return nil
+}
+
+func importMain(cmd *cobra.Command, args []string) error {
+	fmt.Printf(fexcel.Logo())
+
+	manifestPath, fpath := args[0], args[1]
+
+	i, err := fexcel.NewImporter(manifestPath, fpath, globalCfg.FileConfig, headers)
+	if err != nil {
+		return err
+	}
+
+	return i.Import(os.Stdout)
+}
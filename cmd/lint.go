@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/onerobotics/fexcel/fexcel"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint spreadsheet.xlsx",
+	Short: "Check spreadsheet comments for problems",
+	Example: `  fexcel lint spreadsheet.xlsx
+  fexcel lint spreadsheet.xlsx --target 192.168.100.101 --strict
+  fexcel lint spreadsheet.xlsx --rules rules.yaml`,
+	Args: validateLintArgs,
+	RunE: lintMain,
+}
+
+var (
+	lintFormat string
+	lintTarget string
+	strict     bool
+)
+
+func init() {
+	lintCmd.Flags().StringVar(&lintFormat, "format", "table", "output format (table or json)")
+	lintCmd.Flags().StringVar(&lintTarget, "target", "", "also check that ids exist on this target (IP or backup directory)")
+	lintCmd.Flags().BoolVar(&strict, "strict", false, "exit with an error on warnings too")
+	addRulesFlag(lintCmd)
+	rootCmd.AddCommand(lintCmd)
+}
+
+func validateLintArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("requires a spreadsheet")
+	}
+
+	switch lintFormat {
+	case "table", "json":
+	default:
+		return fmt.Errorf("invalid format %q (must be table or json)", lintFormat)
+	}
+
+	return nil
+}
+
+func lintMain(cmd *cobra.Command, args []string) error {
+	if lintFormat == "table" {
+		fmt.Printf(fexcel.Logo())
+	}
+
+	l, err := fexcel.NewLintCommand(args[0], globalCfg, lintTarget)
+	if err != nil {
+		return err
+	}
+
+	l.Rules, err = readRules()
+	if err != nil {
+		return err
+	}
+
+	issues, err := l.Lint()
+	if err != nil {
+		return err
+	}
+
+	if lintFormat == "json" {
+		err = l.FprintJSON(os.Stdout, issues)
+		if err != nil {
+			return err
+		}
+	} else {
+		l.FprintTable(os.Stdout, issues)
+	}
+
+	count := fexcel.CountIssues(issues, fexcel.LintError)
+	if strict {
+		count += fexcel.CountIssues(issues, fexcel.LintWarning)
+	}
+	if count > 0 {
+		// lint issues are not a usage problem
+		cmd.SilenceUsage = true
+		return fmt.Errorf("found %d %s", count, fexcel.Pluralize("problem", count))
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/onerobotics/fexcel/fexcel"
+	"github.com/onerobotics/fexcel/fexcel/mock"
+	"github.com/spf13/cobra"
+)
+
+var mockCmd = &cobra.Command{
+	Use:     "mock-robot ./backup/dir",
+	Short:   "Serve a backup directory as a fake robot for testing",
+	Example: "  fexcel mock-robot ./backup/dir --addr 127.0.0.1:8080\n  fexcel set spreadsheet.xlsx http://127.0.0.1:8080",
+	Args:    validateMockArgs,
+	RunE:    mockMain,
+}
+
+var (
+	mockAddr    string
+	mockPersist bool
+)
+
+func init() {
+	mockCmd.Flags().StringVar(&mockAddr, "addr", "127.0.0.1:8080", "address to listen on")
+	mockCmd.Flags().BoolVar(&mockPersist, "persist", false, "write comment changes back to the backup directory")
+	rootCmd.AddCommand(mockCmd)
+}
+
+func validateMockArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("requires a backup directory")
+	}
+
+	return nil
+}
+
+func mockMain(cmd *cobra.Command, args []string) error {
+	fmt.Printf(fexcel.Logo())
+
+	r, err := mock.NewRobot(args[0], mockPersist)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Serving %s on http://%s\n", args[0], mockAddr)
+	if !mockPersist {
+		fmt.Println("Comment changes are kept in memory. Use --persist to save them.")
+	}
+
+	return http.ListenAndServe(mockAddr, r)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/onerobotics/fexcel/fexcel"
+	"github.com/spf13/cobra"
+)
+
+var pullCmd = &cobra.Command{
+	Use:     "pull spreadsheet.xlsx target",
+	Short:   "Update an existing spreadsheet with a target's comments",
+	Example: "  fexcel pull ./doc/spreadsheet.xlsx 192.168.100.101",
+	Args:    validatePullArgs,
+	RunE:    pullMain,
+}
+
+var (
+	appendMissing bool
+)
+
+func init() {
+	pullCmd.Flags().BoolVar(&appendMissing, "append", false, "append commented ids that are missing from the spreadsheet")
+	rootCmd.AddCommand(pullCmd)
+}
+
+func validatePullArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("requires a spreadsheet path and a target (IP or backup directory)")
+	}
+
+	return nil
+}
+
+func pullMain(cmd *cobra.Command, args []string) error {
+	fmt.Printf(fexcel.Logo())
+
+	fpath, targetPath := args[0], args[1]
+
+	p, err := fexcel.NewPuller(fpath, globalCfg, targetPath, appendMissing)
+	if err != nil {
+		return err
+	}
+
+	return p.Pull(os.Stdout)
+}
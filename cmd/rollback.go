@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/onerobotics/fexcel/fexcel"
+	"github.com/spf13/cobra"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:     "rollback snapshot.json target",
+	Short:   "Restore the comments saved in a snapshot by set",
+	Example: "  fexcel rollback ./snapshots/snapshot-20200108-152301.000.json 192.168.100.101",
+	Args:    validateRollbackArgs,
+	RunE:    rollbackMain,
+}
+
+var (
+	rollbackDryRun bool
+)
+
+func init() {
+	rollbackCmd.Flags().BoolVar(&rollbackDryRun, "dry-run", false, "print the changes that would be made without making them")
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+func validateRollbackArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("requires a snapshot and a target (IP or backup directory)")
+	}
+
+	return nil
+}
+
+func rollbackMain(cmd *cobra.Command, args []string) error {
+	fmt.Printf(fexcel.Logo())
+
+	snapshotPath, host := args[0], args[1]
+
+	r, err := fexcel.NewRollbackCommand(snapshotPath, globalCfg, host)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restoring %s to %s (snapshot of %s)\n", host, r.Snapshot().Time.Format("2006-01-02 15:04:05"), r.Snapshot().File)
+
+	if rollbackDryRun {
+		p, err := r.Plan()
+		if err != nil {
+			return err
+		}
+		p.FprintTable(os.Stdout)
+		return nil
+	}
+
+	count, err := r.Execute()
+	fmt.Printf("Restored %d %s.\n", count, fexcel.Pluralize("comment", count))
+
+	return err
+}
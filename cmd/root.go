@@ -16,6 +16,8 @@ const configFile = ".fexcel.yaml"
 
 var (
 	cfgFile   string
+	rulesFile string
+	tolerance map[string]string
 	save      bool
 	globalCfg fexcel.Config
 )
@@ -64,28 +66,33 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Sheet, "sheet", "Sheet1", "default sheet to look at when unspecified in the start cell")
 	rootCmd.PersistentFlags().IntVar(&globalCfg.FileConfig.Offset, "offset", 1, "column offset between ids and comments")
-
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Constants, "constants", "", "start cell of constant ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Numregs, "numregs", "", "start cell of numeric register ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Posregs, "posregs", "", "start cell of position register ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Sregs, "sregs", "", "start cell of string register ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Ualms, "ualms", "", "start cell of user alarm ids")
-
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Ains, "ains", "", "start cell of analog input ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Aouts, "aouts", "", "start cell of analog output ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Dins, "dins", "", "start cell of digital input ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Douts, "douts", "", "start cell of digital output ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Flags, "flags", "", "start cell of flag ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Gins, "gins", "", "start cell of group input ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Gouts, "gouts", "", "start cell of group output ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Rins, "rins", "", "start cell of robot input ids")
-	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Routs, "routs", "", "start cell of robot output ids")
+	rootCmd.PersistentFlags().IntVar(&globalCfg.FileConfig.ValueOffset, "value-offset", 0, "column offset between ids and R and SR values or the first PR position column (0 for none)")
+	rootCmd.PersistentFlags().StringVar(&globalCfg.FileConfig.Until, "until", "blank", "where lists end: blank, used (the sheet's used range) or a number of consecutive blank ids")
+
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Constants, "constants", nil, "start cell(s) of constant ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Numregs, "numregs", nil, "start cell(s) of numeric register ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Posregs, "posregs", nil, "start cell(s) of position register ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Sregs, "sregs", nil, "start cell(s) of string register ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Ualms, "ualms", nil, "start cell(s) of user alarm ids")
+
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Ains, "ains", nil, "start cell(s) of analog input ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Aouts, "aouts", nil, "start cell(s) of analog output ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Dins, "dins", nil, "start cell(s) of digital input ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Douts, "douts", nil, "start cell(s) of digital output ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Flags, "flags", nil, "start cell(s) of flag ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Gins, "gins", nil, "start cell(s) of group input ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Gouts, "gouts", nil, "start cell(s) of group output ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Rins, "rins", nil, "start cell(s) of robot input ids")
+	rootCmd.PersistentFlags().StringSliceVar(&globalCfg.FileConfig.Routs, "routs", nil, "start cell(s) of robot output ids")
 
 	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
 
 	viper.BindPFlag("fileconfig.sheet", rootCmd.PersistentFlags().Lookup("sheet"))
 	viper.BindPFlag("fileconfig.offset", rootCmd.PersistentFlags().Lookup("offset"))
+	viper.BindPFlag("fileconfig.valueoffset", rootCmd.PersistentFlags().Lookup("value-offset"))
+	viper.BindPFlag("fileconfig.until", rootCmd.PersistentFlags().Lookup("until"))
 
+	viper.BindPFlag("fileconfig.constants", rootCmd.PersistentFlags().Lookup("constants"))
 	viper.BindPFlag("fileconfig.numregs", rootCmd.PersistentFlags().Lookup("numregs"))
 	viper.BindPFlag("fileconfig.posregs", rootCmd.PersistentFlags().Lookup("posregs"))
 	viper.BindPFlag("fileconfig.sregs", rootCmd.PersistentFlags().Lookup("sregs"))
@@ -122,6 +129,67 @@ func initConfig() {
 	}
 }
 
+// addRulesFlag adds --rules to the commands that enforce naming rules
+func addRulesFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&rulesFile, "rules", "", "naming rules file enforced by lint and set")
+}
+
+// readRules reads the naming rules file given by --rules or the config
+// file, if there is one
+func readRules() (*fexcel.Rules, error) {
+	path := rulesFile
+	if path == "" {
+		path = viper.GetString("rules")
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var rules struct {
+		Types  map[string]string
+		Sheets map[string][]string
+	}
+	err = v.Unmarshal(&rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return fexcel.NewRules(rules.Types, rules.Sheets)
+}
+
+// addToleranceFlag adds --tolerance to the commands that compare PR
+// positions
+func addToleranceFlag(cmd *cobra.Command) {
+	cmd.Flags().StringToStringVar(&tolerance, "tolerance", nil, "largest PR position differences by axis, e.g. X=0.5,W=0.1,J=0.01 (default 0.001)")
+}
+
+// readTolerance returns the position tolerance of the config file, with
+// the axes given by --tolerance overriding it
+func readTolerance() (fexcel.Tolerance, error) {
+	flags, err := fexcel.ParseTolerance(tolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	t := make(fexcel.Tolerance)
+	for axis, f := range globalCfg.Tolerance {
+		t[strings.ToUpper(axis)] = f
+	}
+	for axis, f := range flags {
+		t[strings.ToUpper(axis)] = f
+	}
+
+	return t, nil
+}
+
 func validateRootArgs(cmd *cobra.Command, args []string) error {
 	if len(args) != 1 {
 		return errors.New("requires a spreadsheet")
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -14,36 +15,86 @@ import (
 )
 
 var setCmd = &cobra.Command{
-	Use:   "set ./path/to/spreadsheet.xlsx ipAddress [more ipAddresses]",
-	Short: "Set FANUC robots comments based on the provided Excel spreadsheet",
-	Args:  validateSetArgs,
-	RunE:  setMain,
+	Use:     "set ./path/to/spreadsheet.xlsx target(s)...",
+	Short:   "Set FANUC robots comments based on the provided Excel spreadsheet",
+	Example: "  fexcel set spreadsheet.xlsx 192.168.100.101 192.168.100.102 ./backup/dir",
+	Args:    validateSetArgs,
+	RunE:    setMain,
 }
 
+var (
+	dryRun      bool
+	planFormat  string
+	snapshotDir string
+	force       bool
+	setValues   bool
+)
+
 func init() {
+	setCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes that would be made without making them")
+	setCmd.Flags().StringVar(&planFormat, "plan-format", "table", "dry-run output format (table or json)")
+	setCmd.Flags().StringVar(&snapshotDir, "snapshot-dir", "snapshots", "where to save the comments being overwritten (empty to disable)")
+	setCmd.Flags().BoolVar(&force, "force", false, "set comments that break the naming rules (see --rules)")
+	setCmd.Flags().BoolVar(&setValues, "values", false, "set R and SR values and PR positions too (see --value-offset)")
+	addToleranceFlag(setCmd)
+	addRulesFlag(setCmd)
 	rootCmd.AddCommand(setCmd)
 }
 
 func validateSetArgs(cmd *cobra.Command, args []string) error {
 	if len(args) < 2 {
-		return errors.New("requires a spreadsheet and at least one host IP address")
+		return errors.New("requires a spreadsheet and at least one target (IP or backup directory)")
+	}
+
+	switch planFormat {
+	case "table", "json":
+	default:
+		return fmt.Errorf("invalid plan format %q (must be table or json)", planFormat)
 	}
 
 	return nil
 }
 
 func setMain(cmd *cobra.Command, args []string) error {
-	fmt.Printf(fexcel.Logo())
+	if !dryRun || planFormat != "json" {
+		fmt.Printf(fexcel.Logo())
+	}
 
 	fpath, hosts := args[0], args[1:]
 
+	var err error
+	globalCfg.Tolerance, err = readTolerance()
+	if err != nil {
+		return err
+	}
+
 	setCmd, err := fexcel.NewSetCommand(fpath, globalCfg, hosts...)
 	if err != nil {
 		return err
 	}
 
+	setCmd.Values = setValues
+
+	if dryRun {
+		return printPlans(setCmd)
+	}
+
+	setCmd.SnapshotDir = snapshotDir
+	setCmd.Force = force
+
+	setCmd.Rules, err = readRules()
+	if err != nil {
+		return err
+	}
+
 	startTime := time.Now()
 	result, err := setCmd.Execute()
+	printSetWarnings(setCmd)
+	if _, ok := err.(*fexcel.RuleError); ok {
+		// nothing was set
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%s\nNo comments were set. Use --force to set them anyway.", err)
+	}
 	// we will use err later
 
 	table := tablewriter.NewWriter(os.Stdout)
@@ -82,7 +133,40 @@ func setMain(cmd *cobra.Command, args []string) error {
 
 	table.Render()
 
+	if setCmd.SnapshotPath != "" {
+		fmt.Printf("Saved previous comments to %s. Use `fexcel rollback` to restore them.\n", setCmd.SnapshotPath)
+	}
 	fmt.Printf("Finished in %s.\n\n", time.Since(startTime))
 
 	return err
 }
+
+// e.g. values that could not be read
+func printSetWarnings(setCmd *fexcel.SetCommand) {
+	for _, w := range setCmd.Warnings() {
+		fmt.Fprintln(os.Stderr, "Warning:", w)
+	}
+}
+
+func printPlans(setCmd *fexcel.SetCommand) error {
+	// the plans of the targets that could be planned are printed
+	// before the errors of the others
+	plans, err := setCmd.Plan()
+	printSetWarnings(setCmd)
+
+	switch planFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(plans); encErr != nil {
+			return encErr
+		}
+	default:
+		for _, p := range plans {
+			p.FprintTable(os.Stdout)
+			fmt.Println("")
+		}
+	}
+
+	return err
+}
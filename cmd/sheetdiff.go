@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/onerobotics/fexcel/fexcel"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var sheetDiffCmd = &cobra.Command{
+	Use:     "diff-sheets old.xlsx new.xlsx",
+	Short:   "Compare two revisions of a spreadsheet",
+	Example: "  fexcel diff-sheets io-rev1.xlsx io-rev2.xlsx --new-config rev2.yaml",
+	Args:    validateSheetDiffArgs,
+	RunE:    sheetDiffMain,
+}
+
+var (
+	newConfigFile   string
+	sheetDiffFormat string
+)
+
+func init() {
+	sheetDiffCmd.Flags().StringVar(&newConfigFile, "new-config", "", "config file for the new spreadsheet (default is the same config as the old one)")
+	sheetDiffCmd.Flags().StringVar(&sheetDiffFormat, "format", "table", "output format (table or json)")
+	rootCmd.AddCommand(sheetDiffCmd)
+}
+
+func validateSheetDiffArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("requires two spreadsheets")
+	}
+
+	switch sheetDiffFormat {
+	case "table", "json":
+	default:
+		return fmt.Errorf("invalid format %q (must be table or json)", sheetDiffFormat)
+	}
+
+	return nil
+}
+
+// readFileConfig reads the file config from a config file other than
+// the default one
+func readFileConfig(path string) (fexcel.FileConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+
+	err := v.ReadInConfig()
+	if err != nil {
+		return fexcel.FileConfig{}, err
+	}
+
+	cfg := fexcel.Config{FileConfig: fexcel.FileConfig{Sheet: "Sheet1", Offset: 1}}
+	err = v.Unmarshal(&cfg)
+	if err != nil {
+		return fexcel.FileConfig{}, err
+	}
+
+	return cfg.FileConfig, nil
+}
+
+func sheetDiffMain(cmd *cobra.Command, args []string) error {
+	if sheetDiffFormat == "table" {
+		fmt.Printf(fexcel.Logo())
+	}
+
+	oldPath, newPath := args[0], args[1]
+
+	newCfg := globalCfg.FileConfig
+	if newConfigFile != "" {
+		var err error
+		newCfg, err = readFileConfig(newConfigFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	s, err := fexcel.NewSheetDiffCommand(oldPath, globalCfg.FileConfig, newPath, newCfg)
+	if err != nil {
+		return err
+	}
+
+	changes, err := s.Compare()
+	if err != nil {
+		return err
+	}
+
+	if sheetDiffFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(changes)
+	}
+
+	for _, w := range s.Warnings() {
+		fmt.Println("Warning:", w)
+	}
+
+	s.FprintTable(os.Stdout, changes)
+	fmt.Printf("%d %s.\n\n", len(changes), fexcel.Pluralize("change", len(changes)))
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/onerobotics/fexcel/fexcel"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync spreadsheet.xlsx target",
+	Short: "Merge spreadsheet and target comment changes since the last sync",
+	Long: `Merge spreadsheet and target comment changes since the last sync.
+
+Comments changed only in the spreadsheet are written to the target, and
+comments changed only on the target are written to the spreadsheet.
+Comments changed in both places are reported as conflicts and left
+alone. The state after each sync is stored in a baseline file.`,
+	Example: "  fexcel sync ./doc/spreadsheet.xlsx 192.168.100.101",
+	Args:    validateSyncArgs,
+	RunE:    syncMain,
+}
+
+var (
+	baselinePath string
+	syncDryRun   bool
+)
+
+func init() {
+	syncCmd.Flags().StringVar(&baselinePath, "baseline", "", "baseline file (default is spreadsheet.target.sync.json next to the spreadsheet)")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "print the changes that would be made without making them")
+	rootCmd.AddCommand(syncCmd)
+}
+
+func validateSyncArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("requires a spreadsheet path and a target (IP or backup directory)")
+	}
+
+	return nil
+}
+
+func syncMain(cmd *cobra.Command, args []string) error {
+	fmt.Printf(fexcel.Logo())
+
+	fpath, targetPath := args[0], args[1]
+
+	s, err := fexcel.NewSyncer(fpath, globalCfg, targetPath, baselinePath)
+	if err != nil {
+		return err
+	}
+
+	var p *fexcel.SyncPlan
+	if syncDryRun {
+		p, err = s.Plan()
+	} else {
+		p, err = s.Sync()
+	}
+	if err != nil {
+		return err
+	}
+
+	p.FprintTable(os.Stdout)
+	fmt.Printf("%d to target, %d to spreadsheet, %d %s.\n", len(p.ToTarget), len(p.ToSheet), len(p.Conflicts), fexcel.Pluralize("conflict", len(p.Conflicts)))
+	if !syncDryRun {
+		fmt.Printf("Saved baseline to %s.\n", s.BaselinePath())
+	}
+	fmt.Println("")
+
+	if len(p.Conflicts) > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d %s must be resolved manually", len(p.Conflicts), fexcel.Pluralize("conflict", len(p.Conflicts)))
+	}
+
+	return nil
+}
@@ -11,9 +11,9 @@ import (
 
 func TestPrinter(t *testing.T) {
 	p, err := NewPrinter("testdata/test.xlsx", fexcel.FileConfig{
-		Constants: "G2",
-		Numregs:   "A2",
-		Posregs:   "D2",
+		Constants: []string{"G2"},
+		Numregs:   []string{"A2"},
+		Posregs:   []string{"D2"},
 		Sheet:     "Data",
 		Offset:    1,
 	})
@@ -65,10 +65,10 @@ func TestPrinter(t *testing.T) {
 }
 
 func TestGolden(t *testing.T) {
-	p, err := NewPrinter("testdata/test.xlsx", fexcel.FileConfig{
-		Constants: "G2",
-		Numregs:   "A2",
-		Posregs:   "D2",
+	xlsx, err := NewPrinter("testdata/test.xlsx", fexcel.FileConfig{
+		Constants: []string{"G2"},
+		Numregs:   []string{"A2"},
+		Posregs:   []string{"D2"},
 		Sheet:     "Data",
 		Offset:    1,
 	})
@@ -76,42 +76,50 @@ func TestGolden(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// a manifest with the same definitions needs no cell locations
+	manifest, err := NewPrinter("testdata/test.yaml", fexcel.FileConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	filenames := []string{"test"}
-	for _, fname := range filenames {
-		src, err := ioutil.ReadFile(filepath.Join("testdata", fname+".ls"))
-		if err != nil {
-			t.Fatal(err)
-		}
+	for _, p := range []*Printer{xlsx, manifest} {
+		for _, fname := range filenames {
+			src, err := ioutil.ReadFile(filepath.Join("testdata", fname+".ls"))
+			if err != nil {
+				t.Fatal(err)
+			}
 
-		p.Reset()
-		f, err := Parse(fname+".ls", string(src))
-		if err != nil {
-			t.Errorf("Parse(%s): %s", fname+".ls", err)
-			continue
-		}
+			p.Reset()
+			f, err := Parse(fname+".ls", string(src))
+			if err != nil {
+				t.Errorf("Parse(%s): %s", fname+".ls", err)
+				continue
+			}
 
-		err = p.Print(f)
-		if err != nil {
-			t.Errorf("Print(%s): %s", fname+".ls", err)
-			continue
-		}
+			err = p.Print(f)
+			if err != nil {
+				t.Errorf("Print(%s): %s", fname+".ls", err)
+				continue
+			}
 
-		// compare against golden file
-		golden, err := ioutil.ReadFile(filepath.Join("testdata", fname+".golden"))
-		if err != nil {
-			t.Fatal(err)
-		}
+			// compare against golden file
+			golden, err := ioutil.ReadFile(filepath.Join("testdata", fname+".golden"))
+			if err != nil {
+				t.Fatal(err)
+			}
 
-		sLines := strings.Split(p.Output(), "\n")
-		gLines := strings.Split(string(golden), "\n")
+			sLines := strings.Split(p.Output(), "\n")
+			gLines := strings.Split(string(golden), "\n")
 
-		if len(sLines) != len(gLines) {
-			t.Errorf("line count mismatch, src: %d, golden: %d", len(sLines), len(gLines))
-		}
+			if len(sLines) != len(gLines) {
+				t.Errorf("line count mismatch, src: %d, golden: %d", len(sLines), len(gLines))
+			}
 
-		for i, _ := range sLines {
-			if sLines[i] != gLines[i] {
-				t.Errorf("Compare(%s) line %d: %q vs %q", fname+".ls", i+1, sLines[i], gLines[i])
+			for i, _ := range sLines {
+				if sLines[i] != gLines[i] {
+					t.Errorf("Compare(%s) line %d: %q vs %q", fname+".ls", i+1, sLines[i], gLines[i])
+				}
 			}
 		}
 	}
@@ -119,9 +127,9 @@ func TestGolden(t *testing.T) {
 
 func TestPrinterErrors(t *testing.T) {
 	p, err := NewPrinter("testdata/test.xlsx", fexcel.FileConfig{
-		Constants: "G2",
-		Numregs:   "A2",
-		Posregs:   "D2",
+		Constants: []string{"G2"},
+		Numregs:   []string{"A2"},
+		Posregs:   []string{"D2"},
 		Sheet:     "Data",
 		Offset:    1,
 	})
@@ -162,7 +170,7 @@ func TestPrinterErrors(t *testing.T) {
 func TestBuiltinDefinitions(t *testing.T) {
 	p, err := NewPrinter("testdata/test.xlsx", fexcel.FileConfig{
 		Sheet:     "Data",
-		Constants: "G2",
+		Constants: []string{"G2"},
 		Offset:    1,
 	})
 	if err != nil {
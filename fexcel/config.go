@@ -3,37 +3,48 @@ package fexcel
 import (
 	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/360EntSecGroup-Skylar/excelize/v2"
 )
 
+// Each type can be spread across several locations, so every type
+// takes a list of cell specs.
 type FileConfig struct {
-	Constants string
-	Numregs   string // e.g. A2 or Sheet1:A2 or Offset:Sheet1:A2
-	Posregs   string
-	Ualms     string
-	Rins      string
-	Routs     string
-	Dins      string
-	Douts     string
-	Gins      string
-	Gouts     string
-	Ains      string
-	Aouts     string
-	Sregs     string
-	Flags     string
+	Constants []string
+	Numregs   []string // e.g. A2 or Sheet1:A2 or Offset:Sheet1:A2
+	Posregs   []string
+	Ualms     []string
+	Rins      []string
+	Routs     []string
+	Dins      []string
+	Douts     []string
+	Gins      []string
+	Gouts     []string
+	Ains      []string
+	Aouts     []string
+	Sregs     []string
+	Flags     []string
 	Sheet     string
 	Offset    int
+	Until     string // where lists without a known end stop: blank (default), used or a number of consecutive blanks
+
+	ValueOffset int // column offset between ids and R and SR values (or the first PR position column); 0 if there are none
 }
 
 type Config struct {
 	FileConfig
-	NoUpdate bool
-	Timeout  int
+	NoUpdate  bool
+	Timeout   int
+	Tolerance Tolerance // for comparing PR positions
 }
 
 func (c *FileConfig) Specs() []string {
-	return []string{c.Constants, c.Numregs, c.Posregs, c.Ualms, c.Rins, c.Routs, c.Dins, c.Douts, c.Gins, c.Gouts, c.Ains, c.Aouts, c.Sregs, c.Flags}
+	var specs []string
+	for _, s := range [][]string{c.Constants, c.Numregs, c.Posregs, c.Ualms, c.Rins, c.Routs, c.Dins, c.Douts, c.Gins, c.Gouts, c.Ains, c.Aouts, c.Sregs, c.Flags} {
+		specs = append(specs, s...)
+	}
+	return specs
 }
 
 func (c *FileConfig) Count() (i int) {
@@ -51,8 +62,11 @@ func (c *FileConfig) Locations() (map[Type][]*Location, error) {
 
 	locations := make(map[Type][]*Location)
 	for _, t := range types {
-		spec := c.SpecFor(t)
-		if spec != "" {
+		for _, spec := range c.SpecFor(t) {
+			if spec == "" {
+				continue
+			}
+
 			l, err := NewLocation(spec, c.Sheet)
 			if err != nil {
 				return nil, err
@@ -66,20 +80,31 @@ func (c *FileConfig) Locations() (map[Type][]*Location, error) {
 }
 
 func (c *FileConfig) CheckHeaders() error {
-	locations, err := c.Locations()
-	if err != nil {
-		return nil
-	}
+	types := []Type{Constant, Numreg, Posreg, Ualm, Rin, Rout, Din, Dout, Gin, Gout, Ain, Aout, Sreg, Flag}
+
+	for _, t := range types {
+		for _, spec := range c.SpecFor(t) {
+			if spec == "" {
+				continue
+			}
+
+			loc, err := NewLocation(spec, c.Sheet)
+			if err != nil {
+				return nil
+			}
+
+			// headers and defined names can't be checked without the file
+			if !loc.Resolved() {
+				continue
+			}
 
-	for t, locs := range locations {
-		for _, loc := range locs {
 			_, row, err := excelize.CellNameToCoordinates(loc.Axis)
 			if err != nil {
 				return err
 			}
 
 			if row < 2 {
-				return fmt.Errorf("Cell spec for %ss (%s) must be in row 2 or lower for headers option", t, c.SpecFor(t))
+				return fmt.Errorf("Cell spec for %ss (%s) must be in row 2 or lower for headers option", t, spec)
 			}
 		}
 	}
@@ -94,8 +119,14 @@ func (c *FileConfig) HasOverlaps() (bool, error) {
 	}
 
 	sheets := make(map[string]map[int]bool)
-	for _, locs := range locations {
+	for t, locs := range locations {
+		first, last := c.columnsOf(t)
 		for _, loc := range locs {
+			// only known once the file is opened
+			if !loc.Resolved() {
+				continue
+			}
+
 			if _, defined := sheets[loc.Sheet]; !defined {
 				sheets[loc.Sheet] = make(map[int]bool)
 			}
@@ -108,7 +139,7 @@ func (c *FileConfig) HasOverlaps() (bool, error) {
 			// we consider the start Axis all the way through the offset to be an overlap
 			// e.g. numregs starting in column A with an offset of 5 will
 			// prevent other items from using columns A, B, C, D and E
-			for i := col; i <= col+c.Offset; i++ {
+			for i := col + first; i <= col+last; i++ {
 				if sheets[loc.Sheet][i] {
 					return true, nil
 				} else {
@@ -121,6 +152,30 @@ func (c *FileConfig) HasOverlaps() (bool, error) {
 	return false, nil
 }
 
+// columnsOf returns the first and last columns used by a location of
+// t relative to its id column: the comments and, when there is a value
+// column, the values (or every PR position column).
+func (c *FileConfig) columnsOf(t Type) (first, last int) {
+	cols := []int{0, c.Offset}
+	if c.ValueOffset != 0 && HasValues(t) {
+		cols = append(cols, c.ValueOffset)
+		if t == Posreg {
+			cols = append(cols, c.ValueOffset+positionColumns-1)
+		}
+	}
+
+	for _, col := range cols {
+		if col < first {
+			first = col
+		}
+		if col > last {
+			last = col
+		}
+	}
+
+	return first, last
+}
+
 func (c *FileConfig) Validate() error {
 	if c.Count() < 1 {
 		return errors.New("no cell locations defined")
@@ -130,10 +185,40 @@ func (c *FileConfig) Validate() error {
 		return errors.New("offset must be nonzero")
 	}
 
+	if c.ValueOffset != 0 && c.ValueOffset == c.Offset {
+		return errors.New("value offset must be different from offset")
+	}
+
+	if len(c.Posregs) > 0 && c.ValueOffset != 0 && c.Offset > c.ValueOffset && c.Offset < c.ValueOffset+positionColumns {
+		return errors.New("PR position columns must not include the comment column (see offset and value offset)")
+	}
+
+	if _, err := c.blanks(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (c *FileConfig) SpecFor(t Type) string {
+// blanks returns the number of consecutive blank ids that end a list,
+// or zero if lists end at the last used row of the sheet
+func (c *FileConfig) blanks() (int, error) {
+	switch c.Until {
+	case "", "blank":
+		return 1, nil
+	case "used":
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(c.Until)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("until must be \"blank\", \"used\" or a number of consecutive blank rows, not %q", c.Until)
+	}
+
+	return n, nil
+}
+
+func (c *FileConfig) SpecFor(t Type) []string {
 	switch t {
 	case Constant:
 		return c.Constants
@@ -165,5 +250,5 @@ func (c *FileConfig) SpecFor(t Type) string {
 		return c.Flags
 	}
 
-	return ""
+	return nil
 }
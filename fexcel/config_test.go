@@ -10,21 +10,27 @@ func TestHasOverlaps(t *testing.T) {
 		posregs     string
 		dins        string
 		offset      int
+		valueOffset int
 		hasOverlaps bool
 	}{
-		{"A1", "C1", "E1", 1, false},
-		{"A1", "C1", "E1", 2, true},
-		{"A1", "Foo:C1", "Bar:E1", 2, false}, // no overlap because on different sheets
-		{"A1", "B1", "C1", 3, true},          // not _really_, but this is a stupid spreadsheet design
+		{"A1", "C1", "E1", 1, 0, false},
+		{"A1", "C1", "E1", 2, 0, true},
+		{"A1", "Foo:C1", "Bar:E1", 2, 0, false}, // no overlap because on different sheets
+		{"A1", "B1", "C1", 3, 0, true},          // not _really_, but this is a stupid spreadsheet design
+		{"A1", "", "D1", 1, 2, false},           // R values in C
+		{"A1", "", "C1", 1, 2, true},            // DI ids in the R value column
+		{"A1", "Foo:A1", "Foo:K1", 1, 2, true},  // PR positions run from C to K
+		{"A1", "Foo:A1", "Foo:L1", 1, 2, false},
 	}
 
 	for id, test := range tests {
 		cfg := FileConfig{
-			Numregs: test.numregs,
-			Posregs: test.posregs,
-			Dins:    test.dins,
-			Offset:  test.offset,
-			Sheet:   "Default",
+			Numregs:     []string{test.numregs},
+			Posregs:     []string{test.posregs},
+			Dins:        []string{test.dins},
+			Offset:      test.offset,
+			ValueOffset: test.valueOffset,
+			Sheet:       "Default",
 		}
 
 		result, err := cfg.HasOverlaps()
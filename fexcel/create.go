@@ -52,54 +52,98 @@ func NewCreator(path string, cfg Config, headers bool, targetPath string) (*Crea
 func (c *Creator) Create(w io.Writer) error {
 	fmt.Fprintf(w, "Creating file: %s\n", c.file.path)
 
-	for t, location := range c.file.Locations {
+	for t := range c.file.Locations {
 		fmt.Fprintf(w, "Reading target %s comments\n", t)
 		err := c.target.GetComments(t)
 		if err != nil {
 			return err
 		}
 
-		// create sheet if necessary
-		c.file.CreateSheet(location.Sheet)
+		// maps are not ordered, so let's create an ids slice we can sort
+		var ids []int
+		for id, _ := range c.target.Comments[t] {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
 
-		// get start position
-		col, row, err := excelize.CellNameToCoordinates(location.Axis)
+		var values []interface{}
+		var comments []string
+		for _, id := range ids {
+			values = append(values, id)
+			comments = append(comments, c.target.Comments[t][id])
+		}
+
+		fmt.Fprintf(w, "Writing %d %s comments\n", len(ids), t)
+		err = c.file.writeList(t, c.headers, values, comments, nil)
 		if err != nil {
 			return err
 		}
+	}
 
-		// write header
-		if c.headers {
-			err = c.file.SetValue(location.Sheet, col, row-1, t.String()+"s")
-			if err != nil {
-				return err
-			}
+	fmt.Fprintln(w, "Saving file.")
+	return c.file.Save()
+}
+
+// writeList writes ids and their comments (and values, if any) to the
+// first location of t, with an optional header above them. Values are
+// written as text so reals keep their decimal places.
+func (f *File) writeList(t Type, header bool, ids []interface{}, comments []string, values []string) error {
+	// everything is written to the first location
+	location := f.Locations[t][0]
+
+	// create sheet if necessary
+	err := f.CreateSheet(location.Sheet)
+	if err != nil {
+		return err
+	}
+
+	// get start position
+	col, row, err := excelize.CellNameToCoordinates(location.Axis)
+	if err != nil {
+		return err
+	}
+
+	if header {
+		err = f.SetValue(location.Sheet, col, row-1, t.String()+"s")
+		if err != nil {
+			return err
 		}
+	}
 
-		// maps are not ordered, so let's create an ids slice we can sort
-		var ids []int
-		for id, _ := range c.target.Comments[t] {
-			ids = append(ids, id)
+	for i, id := range ids {
+		err := f.SetValue(location.Sheet, col, row, id)
+		if err != nil {
+			return err
 		}
-		sort.Ints(ids)
 
-		fmt.Fprintf(w, "Writing %d %s comments\n", len(ids), t)
-		for _, id := range ids {
-			comment := c.target.Comments[t][id]
-			err := c.file.SetValue(location.Sheet, col, row, id)
-			if err != nil {
-				return err
-			}
+		err = f.SetValue(location.Sheet, col+f.offsetFor(location), row, comments[i])
+		if err != nil {
+			return err
+		}
 
-			err = c.file.SetValue(location.Sheet, col+c.file.Config.Offset, row, comment)
-			if err != nil {
-				return err
+		if f.Config.ValueOffset != 0 && i < len(values) && values[i] != "" {
+			cells := []string{values[i]}
+			if t == Posreg {
+				p, err := ParsePosition(values[i])
+				if err != nil {
+					return fmt.Errorf("%s[%v]: %s", t, id, err)
+				}
+				cells = nil
+				if p != nil {
+					cells = p.cells()
+				}
 			}
 
-			row++
+			for j, cell := range cells {
+				err = f.SetValue(location.Sheet, col+f.Config.ValueOffset+j, row, cell)
+				if err != nil {
+					return err
+				}
+			}
 		}
+
+		row++
 	}
 
-	fmt.Fprintln(w, "Saving file.")
-	return c.file.Save()
+	return nil
 }
@@ -9,7 +9,7 @@ import (
 
 func TestNewCreator(t *testing.T) {
 	cfg := Config{
-		FileConfig: FileConfig{Offset: 1, Sheet: "Sheet1", Numregs: "A1"},
+		FileConfig: FileConfig{Offset: 1, Sheet: "Sheet1", Numregs: []string{"A1"}},
 	}
 
 	// must be an xlsx file
@@ -49,7 +49,7 @@ func TestNewCreator(t *testing.T) {
 	}
 
 	// overlaps
-	cfg.FileConfig = FileConfig{Offset: 1, Sheet: "Sheet1", Numregs: "A2", Posregs: "B2"}
+	cfg.FileConfig = FileConfig{Offset: 1, Sheet: "Sheet1", Numregs: []string{"A2"}, Posregs: []string{"B2"}}
 	_, err = NewCreator("./testdata/test2.xlsx", cfg, false, "testdata")
 	if err == nil {
 		t.Error("Expected an overlap error. Got none.")
@@ -69,7 +69,7 @@ func TestCreatorCreate(t *testing.T) {
 
 	fpath := filepath.Join(dir, "test.xlsx")
 	cfg := Config{
-		FileConfig: FileConfig{Offset: 1, Sheet: "Sheet1", Numregs: "A2"},
+		FileConfig: FileConfig{Offset: 1, Sheet: "Sheet1", Numregs: []string{"A2"}},
 		Timeout:    500,
 	}
 
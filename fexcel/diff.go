@@ -1,19 +1,26 @@
 package fexcel
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"path/filepath"
+	"sort"
 	"strconv"
 
 	"github.com/olekukonko/tablewriter"
 )
 
 type DiffCommand struct {
-	fpath string
+	fpath     string
+	tolerance Tolerance
 
-	file    *File
-	targets []*Target
+	file      *File
+	reference *Target // compared against instead of a file
+	types     []Type  // to compare when there is no file
+	targets   []*Target
 }
 
 func NewDiffCommand(fpath string, cfg Config, targetPaths ...string) (*DiffCommand, error) {
@@ -21,11 +28,17 @@ func NewDiffCommand(fpath string, cfg Config, targetPaths ...string) (*DiffComma
 		return nil, fmt.Errorf("Need at least one target")
 	}
 
-	if cfg.FileConfig.Count() == 0 {
+	// a manifest has no cell locations
+	if cfg.FileConfig.Count() == 0 && !isManifest(fpath) {
 		return nil, fmt.Errorf("no cell locations defined")
 	}
 
-	d := DiffCommand{fpath: fpath}
+	err := cfg.Tolerance.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	d := DiffCommand{fpath: fpath, tolerance: cfg.Tolerance}
 
 	for _, path := range targetPaths {
 		t, err := NewTarget(path, cfg.Timeout)
@@ -44,15 +57,44 @@ func NewDiffCommand(fpath string, cfg Config, targetPaths ...string) (*DiffComma
 	return &d, nil
 }
 
+// NewTargetDiffCommand compares targets to each other instead of a
+// spreadsheet. The first target is used as the reference. If no types
+// are provided, every type with comments is compared.
+func NewTargetDiffCommand(cfg Config, types []Type, targetPaths ...string) (*DiffCommand, error) {
+	if len(targetPaths) < 2 {
+		return nil, fmt.Errorf("Need at least two targets")
+	}
+
+	if len(types) == 0 {
+		types = commentTypes
+	}
+
+	d := DiffCommand{types: types}
+
+	for _, path := range targetPaths {
+		t, err := NewTarget(path, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		d.targets = append(d.targets, t)
+	}
+
+	d.reference, d.targets = d.targets[0], d.targets[1:]
+
+	return &d, nil
+}
+
 type Comparison struct {
 	Id   int
 	Want string
-	Got  map[string]string
+	Got  []string // one per target, in the same order as the targets
+
+	matches []bool // per target, when Got doesn't have to equal Want exactly
 }
 
 func (c Comparison) Equal() bool {
-	for _, got := range c.Got {
-		if got != c.Want {
+	for i := range c.Got {
+		if !c.EqualAt(i) {
 			return false
 		}
 	}
@@ -60,21 +102,29 @@ func (c Comparison) Equal() bool {
 	return true
 }
 
+// EqualAt reports whether the ith target matches. PR positions only
+// have to be within the tolerance.
+func (c Comparison) EqualAt(i int) bool {
+	if c.matches != nil {
+		return c.matches[i]
+	}
+	return c.Got[i] == c.Want
+}
+
 func (c Comparison) row() []string {
 	diff := " "
 	if !c.Equal() {
 		diff = "X"
 	}
 
-	row := []string{strconv.Itoa(c.Id), diff, c.Want}
-	for _, s := range c.Got {
-		row = append(row, s)
-	}
-
-	return row
+	return append([]string{strconv.Itoa(c.Id), diff, c.Want}, c.Got...)
 }
 
 func (d *DiffCommand) Compare(t Type) (comparisons []Comparison, err error) {
+	if d.file == nil {
+		return d.compareTargets(t)
+	}
+
 	definitions, err := d.file.Definitions(t)
 	if err != nil {
 		return
@@ -93,14 +143,13 @@ func (d *DiffCommand) Compare(t Type) (comparisons []Comparison, err error) {
 	// let's only diff the ones defined in the spreadsheet
 	for _, def := range definitions {
 		c := Comparison{Id: def.Id, Want: def.Comment}
-		c.Got = make(map[string]string)
 
 		for _, target := range d.targets {
 			got := "undefined"
 			if comment, ok := target.Comments[t][def.Id]; ok {
 				got = comment
 			}
-			c.Got[target.Name] = got
+			c.Got = append(c.Got, got)
 		}
 
 		comparisons = append(comparisons, c)
@@ -109,24 +158,169 @@ func (d *DiffCommand) Compare(t Type) (comparisons []Comparison, err error) {
 	return
 }
 
-func (d *DiffCommand) FprintTable(w io.Writer, t Type, all bool) error {
-	comparisons, err := d.Compare(t)
+// compareTargets compares the union of every id found on the
+// reference and the other targets.
+func (d *DiffCommand) compareTargets(t Type) (comparisons []Comparison, err error) {
+	ids := make(map[int]bool)
+	for _, target := range append([]*Target{d.reference}, d.targets...) {
+		err = target.GetComments(t)
+		if err != nil {
+			return
+		}
+
+		for id := range target.Comments[t] {
+			ids[id] = true
+		}
+	}
+
+	var sorted []int
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Ints(sorted)
+
+	for _, id := range sorted {
+		want, ok := d.reference.Comments[t][id]
+		if !ok {
+			want = "undefined"
+		}
+
+		c := Comparison{Id: id, Want: want}
+
+		for _, target := range d.targets {
+			got := "undefined"
+			if comment, ok := target.Comments[t][id]; ok {
+				got = comment
+			}
+			c.Got = append(c.Got, got)
+		}
+
+		comparisons = append(comparisons, c)
+	}
+
+	return
+}
+
+// CompareValues compares the values of the definitions of t that have
+// one (see HasValues) to the targets' current values. PR positions are
+// compared within the tolerance.
+func (d *DiffCommand) CompareValues(t Type) (comparisons []Comparison, err error) {
+	if d.file == nil || !HasValues(t) {
+		return
+	}
+
+	definitions, err := d.file.Definitions(t)
 	if err != nil {
-		return err
+		return
+	}
+
+	for _, target := range d.targets {
+		err = target.GetValues(t)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, def := range definitions {
+		if def.Value == "" {
+			continue
+		}
+
+		c := Comparison{Id: def.Id, Want: def.Value}
+
+		for _, target := range d.targets {
+			got, ok := target.Values[t][def.Id]
+			if !ok {
+				got = "undefined"
+				if _, unreadable := target.ValueErrors[t][def.Id]; unreadable {
+					got = "unreadable"
+				}
+			}
+			c.Got = append(c.Got, got)
+			c.matches = append(c.matches, ok && valuesMatch(t, def.Value, got, d.tolerance))
+		}
+
+		comparisons = append(comparisons, c)
 	}
 
-	fmt.Fprintf(w, "%ss\n", t)
+	return
+}
+
+// A Result holds the comparisons for a single type.
+type Result struct {
+	Type        Type
+	Values      bool // compares values instead of comments
+	Comparisons []Comparison
+}
+
+// label names the type and whether values are compared, e.g. "R value"
+func (r Result) label() string {
+	if r.Values {
+		return r.Type.String() + " value"
+	}
+	return r.Type.String()
+}
+
+// Differences returns the number of unequal comparisons.
+func (r Result) Differences() (i int) {
+	for _, c := range r.Comparisons {
+		if !c.Equal() {
+			i++
+		}
+	}
+
+	return i
+}
+
+// CompareAll compares every type defined in the spreadsheet. Values
+// are compared after the comments of their type.
+func (d *DiffCommand) CompareAll() ([]Result, error) {
+	// d.types may be shared, e.g. commentTypes, so sort a copy
+	types := append([]Type(nil), d.types...)
+	if d.file != nil {
+		types = nil
+		for t := range d.file.Locations {
+			if t != Constant {
+				types = append(types, t)
+			}
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	var results []Result
+	for _, t := range types {
+		comparisons, err := d.Compare(t)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, Result{Type: t, Comparisons: comparisons})
+
+		comparisons, err = d.CompareValues(t)
+		if err != nil {
+			return nil, err
+		}
+		if len(comparisons) > 0 {
+			results = append(results, Result{Type: t, Values: true, Comparisons: comparisons})
+		}
+	}
+
+	return results, nil
+}
+
+func (d *DiffCommand) FprintTable(w io.Writer, r Result, all bool) error {
+	fmt.Fprintf(w, "%ss\n", r.label())
 	table := tablewriter.NewWriter(w)
 	table.SetAutoWrapText(false)
 	table.SetAutoFormatHeaders(false)
 
-	header := []string{"Id", "Diff", filepath.Base(d.fpath)}
+	header := []string{"Id", "Diff", d.source()}
 	for _, target := range d.targets {
 		header = append(header, target.Name)
 	}
 	table.SetHeader(header)
 
-	for _, c := range comparisons {
+	for _, c := range r.Comparisons {
 		if all || !c.Equal() {
 			table.Append(c.row())
 		}
@@ -137,10 +331,180 @@ func (d *DiffCommand) FprintTable(w io.Writer, t Type, all bool) error {
 	return nil
 }
 
-func (d *DiffCommand) Locations() map[Type]*Location {
+func (d *DiffCommand) FprintJSON(w io.Writer, results []Result, all bool) error {
+	// one of comment or value is set, depending on the result
+	type got struct {
+		Target  string  `json:"target"`
+		Comment *string `json:"comment,omitempty"`
+		Value   *string `json:"value,omitempty"`
+	}
+	type comparison struct {
+		Id    int    `json:"id"`
+		Equal bool   `json:"equal"`
+		Want  string `json:"want"`
+		Got   []got  `json:"got"`
+	}
+	type result struct {
+		Type        Type         `json:"type"`
+		Values      bool         `json:"values,omitempty"`
+		Comparisons []comparison `json:"comparisons"`
+	}
+
+	out := struct {
+		Source  string   `json:"source"`
+		Targets []string `json:"targets"`
+		Results []result `json:"results"`
+	}{Source: d.source(), Targets: d.targetNames(), Results: []result{}}
+
+	for _, r := range results {
+		res := result{Type: r.Type, Values: r.Values, Comparisons: []comparison{}}
+		for _, c := range r.Comparisons {
+			if all || !c.Equal() {
+				cmp := comparison{Id: c.Id, Equal: c.Equal(), Want: c.Want}
+				for i, t := range d.targets {
+					g := got{Target: t.Name}
+					if r.Values {
+						g.Value = &c.Got[i]
+					} else {
+						g.Comment = &c.Got[i]
+					}
+					cmp.Got = append(cmp.Got, g)
+				}
+				res.Comparisons = append(res.Comparisons, cmp)
+			}
+		}
+		out.Results = append(out.Results, res)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func (d *DiffCommand) FprintCSV(w io.Writer, results []Result, all bool) error {
+	cw := csv.NewWriter(w)
+
+	header := append([]string{"Type", "Id", "Diff", d.source()}, d.targetNames()...)
+	err := cw.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		for _, c := range r.Comparisons {
+			if !all && c.Equal() {
+				continue
+			}
+
+			diff := ""
+			if !c.Equal() {
+				diff = "X"
+			}
+
+			record := append([]string{r.label(), strconv.Itoa(c.Id), diff, c.Want}, c.Got...)
+
+			err = cw.Write(record)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// FprintJUnit writes a JUnit XML report with a test suite per target
+// and a test case per id (and value). Unequal comments and values are
+// reported as failures.
+func (d *DiffCommand) FprintJUnit(w io.Writer, results []Result) error {
+	type failure struct {
+		Message string `xml:"message,attr"`
+	}
+	type testcase struct {
+		Name      string   `xml:"name,attr"`
+		Classname string   `xml:"classname,attr"`
+		Failure   *failure `xml:"failure,omitempty"`
+	}
+	type testsuite struct {
+		Name      string     `xml:"name,attr"`
+		Tests     int        `xml:"tests,attr"`
+		Failures  int        `xml:"failures,attr"`
+		Testcases []testcase `xml:"testcase"`
+	}
+	type testsuites struct {
+		XMLName  xml.Name    `xml:"testsuites"`
+		Name     string      `xml:"name,attr"`
+		Tests    int         `xml:"tests,attr"`
+		Failures int         `xml:"failures,attr"`
+		Suites   []testsuite `xml:"testsuite"`
+	}
+
+	out := testsuites{Name: d.source()}
+	for i, name := range d.targetNames() {
+		suite := testsuite{Name: name}
+		for _, r := range results {
+			for _, c := range r.Comparisons {
+				name := fmt.Sprintf("%s[%d]", r.Type, c.Id)
+				if r.Values {
+					name += " value"
+				}
+				tc := testcase{Name: name, Classname: r.Type.String()}
+				if !c.EqualAt(i) {
+					tc.Failure = &failure{Message: fmt.Sprintf("got %q, want %q", c.Got[i], c.Want)}
+					suite.Failures++
+				}
+				suite.Testcases = append(suite.Testcases, tc)
+				suite.Tests++
+			}
+		}
+		out.Tests += suite.Tests
+		out.Failures += suite.Failures
+		out.Suites = append(out.Suites, suite)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(out)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w)
+	return err
+}
+
+// source returns the name of whatever the targets are compared to
+func (d *DiffCommand) source() string {
+	if d.file == nil {
+		return d.reference.Name
+	}
+	return filepath.Base(d.fpath)
+}
+
+func (d *DiffCommand) targetNames() []string {
+	var names []string
+	for _, t := range d.targets {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func (d *DiffCommand) Locations() map[Type][]*Location {
+	if d.file == nil {
+		return nil
+	}
 	return d.file.Locations
 }
 
 func (d *DiffCommand) Warnings() []string {
+	if d.file == nil {
+		return nil
+	}
 	return d.file.Warnings
 }
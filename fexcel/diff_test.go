@@ -1,13 +1,16 @@
 package fexcel
 
 import (
+	"encoding/json"
+	"os"
+	"strings"
 	"testing"
 )
 
 func TestDiffCompare(t *testing.T) {
 	cfg := Config{
 		FileConfig: FileConfig{
-			Numregs: "Data:A2",
+			Numregs: []string{"Data:A2"},
 			Offset:  1,
 		},
 	}
@@ -43,11 +46,203 @@ func TestDiffCompare(t *testing.T) {
 		if result.Want != want.want {
 			t.Errorf("Bad want. Got %q, want %q", result.Want, want.want)
 		}
-		if result.Got["testdata"] != want.got {
-			t.Errorf("Bad got. Got %q, want %q", result.Got["testdata"], want.got)
+		if len(result.Got) != 1 || result.Got[0] != want.got {
+			t.Errorf("Bad got. Got %q, want %q", result.Got, want.got)
 		}
 		if result.Equal() != want.eql {
 			t.Errorf("Bad eql. Got %t, want %t", result.Equal(), want.eql)
 		}
 	}
 }
+
+func TestDiffFormats(t *testing.T) {
+	cfg := Config{
+		FileConfig: FileConfig{
+			Sheet:   "Data",
+			Numregs: []string{"A2"},
+			Posregs: []string{"D2"},
+			Offset:  1,
+		},
+	}
+
+	cmd, err := NewDiffCommand("testdata/test.xlsx", cfg, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := cmd.CompareAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].Type != Numreg || results[1].Type != Posreg {
+		t.Fatalf("Expected results for R and PR. Got %v", results)
+	}
+	if results[0].Differences() != 1 || results[1].Differences() != 1 {
+		t.Errorf("Expected 1 difference per type")
+	}
+
+	var b strings.Builder
+	err = cmd.FprintCSV(&b, results, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Type,Id,Diff,test.xlsx,testdata\nR,1,X,this is an extremely long comment,this is an extre\nPR,1,X,pr1,Maintenance\n"
+	if b.String() != want {
+		t.Errorf("Bad CSV. Got %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	err = cmd.FprintJSON(&b, results, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Results []struct {
+			Type        Type
+			Comparisons []struct {
+				Id    int
+				Equal bool
+				Got   []struct {
+					Target  string
+					Comment string
+				}
+			}
+		}
+	}
+	err = json.Unmarshal([]byte(b.String()), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Results) != 2 || out.Results[1].Type != Posreg || len(out.Results[1].Comparisons) != 5 {
+		t.Fatalf("Bad JSON: %s", b.String())
+	}
+	if c := out.Results[1].Comparisons[0]; c.Equal || len(c.Got) != 1 || c.Got[0].Target != "testdata" || c.Got[0].Comment != "Maintenance" {
+		t.Errorf("Bad JSON comparison for PR[1]: %v", c)
+	}
+
+	b.Reset()
+	err = cmd.FprintJUnit(&b, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`<testsuite name="testdata" tests="10" failures="2">`, `<testcase name="PR[2]" classname="PR"></testcase>`} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("JUnit output missing %s", s)
+		}
+	}
+}
+
+func TestDiffTargetOrder(t *testing.T) {
+	dir := tempBackup(t)
+	defer os.RemoveAll(dir)
+
+	other, err := NewTarget(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = other.SetComment(Numreg, 2, "deux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = other.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{FileConfig: FileConfig{Numregs: []string{"Data:A2"}, Offset: 1}}
+
+	// the target columns must follow the header regardless of map ordering
+	for i := 0; i < 10; i++ {
+		cmd, err := NewDiffCommand("testdata/test.xlsx", cfg, "testdata", dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		results, err := cmd.Compare(Numreg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"2", "X", "two", "two", "deux"}
+		row := results[1].row()
+		if strings.Join(row, ",") != strings.Join(want, ",") {
+			t.Fatalf("Bad row. Got %q, want %q", row, want)
+		}
+	}
+}
+
+func TestTargetDiff(t *testing.T) {
+	dir := tempBackup(t)
+	defer os.RemoveAll(dir)
+
+	other, err := NewTarget(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = other.SetComment(Numreg, 2, "deux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = other.SetComment(Rin, 3, "rin3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = other.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewTargetDiffCommand(Config{}, nil, "testdata")
+	if err == nil {
+		t.Error("Expected an error for a single target")
+	}
+
+	types := []Type{Rin, Numreg, Sreg}
+	cmd, err := NewTargetDiffCommand(Config{}, types, "testdata", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := cmd.CompareAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the caller's types are left alone
+	if types[0] != Rin || types[1] != Numreg || types[2] != Sreg {
+		t.Errorf("types were reordered: %v", types)
+	}
+
+	counts := []struct {
+		typ         Type
+		comparisons int
+		differences int
+	}{
+		{Numreg, 200, 1},
+		{Rin, 8, 1},
+		{Sreg, 25, 0},
+	}
+
+	if len(results) != len(counts) {
+		t.Fatalf("Got %d results, want %d", len(results), len(counts))
+	}
+
+	for i, want := range counts {
+		r := results[i]
+		if r.Type != want.typ {
+			t.Errorf("Bad type order. Got %s, want %s", r.Type, want.typ)
+		}
+		if len(r.Comparisons) != want.comparisons {
+			t.Errorf("Got %d %s comparisons, want %d", len(r.Comparisons), r.Type, want.comparisons)
+		}
+		if r.Differences() != want.differences {
+			t.Errorf("Got %d %s differences, want %d", r.Differences(), r.Type, want.differences)
+		}
+	}
+
+	c := results[0].Comparisons[1]
+	if c.Id != 2 || c.Want != "two" || c.Got[0] != "deux" {
+		t.Errorf("Bad comparison for R[2]: %v", c)
+	}
+}
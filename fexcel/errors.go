@@ -2,7 +2,10 @@ package fexcel
 
 import (
 	"fmt"
+	"strings"
 	"sync"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
 )
 
 type errorList struct {
@@ -18,7 +21,7 @@ func (e *errorList) Add(err error) int {
 	return len(e.Errors)
 }
 
-func (e errorList) Error() string {
+func (e *errorList) Error() string {
 	switch len(e.Errors) {
 	case 0:
 		return "no errors"
@@ -28,9 +31,73 @@ func (e errorList) Error() string {
 	return fmt.Sprintf("%s (and %d more errors)", e.Errors[0], len(e.Errors)-1)
 }
 
-func (e errorList) Err() error {
+func (e *errorList) Err() error {
 	if len(e.Errors) == 0 {
 		return nil
 	}
 	return e
 }
+
+// A ReadError is a problem with a cell in the spreadsheet.
+type ReadError struct {
+	Sheet string
+	Axis  string // e.g. A2
+	Type  Type
+	Value string // the raw cell value
+	Msg   string
+}
+
+func newReadError(t Type, sheet string, col, row int, value string, format string, args ...interface{}) *ReadError {
+	axis, _ := excelize.CoordinatesToCellName(col, row)
+	return &ReadError{Sheet: sheet, Axis: axis, Type: t, Value: value, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("[%s]%s: %s", e.Sheet, e.Axis, e.Msg)
+}
+
+// A ReadErrorList collects every ReadError found while reading, so
+// they can all be fixed at once.
+type ReadErrorList []*ReadError
+
+func (p *ReadErrorList) Add(err *ReadError) {
+	*p = append(*p, err)
+}
+
+// collect adds err to the list if it is a ReadError or ReadErrorList
+// and returns any other error.
+func (p *ReadErrorList) collect(err error) error {
+	switch e := err.(type) {
+	case *ReadError:
+		p.Add(e)
+	case ReadErrorList:
+		*p = append(*p, e...)
+	default:
+		return err
+	}
+	return nil
+}
+
+func (p ReadErrorList) Error() string {
+	switch len(p) {
+	case 0:
+		return "no errors"
+	case 1:
+		return p[0].Error()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d errors reading spreadsheet:", len(p))
+	for _, e := range p {
+		fmt.Fprintf(&b, "\n  %s", e)
+	}
+	return b.String()
+}
+
+// Err returns nil if the list is empty.
+func (p ReadErrorList) Err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return p
+}
@@ -3,6 +3,8 @@ package fexcel
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -13,53 +15,171 @@ type Location struct {
 	Axis   string // e.g. A2
 	Sheet  string
 	Offset int
+
+	// Locations can also be given by a defined name, a column header or
+	// the columns of a table. These are resolved to an Axis when the
+	// spreadsheet is opened.
+	Name          string // e.g. DI_LIST
+	Table         string // e.g. DiTable
+	Header        string // e.g. DI
+	CommentHeader string // defaults to Comment
+
+	End    int // last row to read, if known
+	Blanks int // consecutive blank ids that end the list otherwise. Zero reads the sheet's used range
+}
+
+// Resolved reports whether the location has been resolved to a cell
+func (l *Location) Resolved() bool {
+	return l.Axis != ""
 }
 
+// String returns the location in the [Offset:]Sheet:Cell form
+func (l *Location) String() string {
+	if !l.Resolved() {
+		switch {
+		case l.Name != "":
+			return l.Name
+		case l.Table != "":
+			return fmt.Sprintf("%s[%s][%s]", l.Table, l.Header, l.CommentHeader)
+		}
+		return fmt.Sprintf("%s:[%s]", l.Sheet, l.Header)
+	}
+
+	if l.Offset != 0 {
+		return fmt.Sprintf("%d:%s:%s", l.Offset, l.Sheet, l.Axis)
+	}
+	return l.Sheet + ":" + l.Axis
+}
+
+var (
+	cellRegexp   = regexp.MustCompile(`^[A-Za-z]{1,3}\d+$`)
+	nameRegexp   = regexp.MustCompile(`^[A-Za-z_\\][A-Za-z0-9_.\\]*$`)
+	headerRegexp = regexp.MustCompile(`^\[([^\]]+)\](?:\[([^\]]+)\])?$`)
+	tableRegexp  = regexp.MustCompile(`^([A-Za-z_\\][A-Za-z0-9_.\\]*)\[([^\]]+)\](?:\[([^\]]+)\])?$`)
+)
+
 // returns a Location based on a cell specification
 // spec can be in the following forms:
 //
-//   Offset:Sheet:Cell
-//          Sheet:Cell
-//                Cell
+//   Offset:Sheet:Cell[:EndCell]
+//          Sheet:Cell[:EndCell]
+//                Cell[:EndCell]
+//   Offset:Sheet:[Header]
+//          Sheet:[Header][CommentHeader]
+//                [Header][CommentHeader]
+//                Name
+//                Table[IdColumn][CommentColumn]
 //
 // if the sheet is not provided in the spec, the default
 // sheet is used.
 //
+// An EndCell (e.g. A2:A500) reads every row up to and including its
+// row. If it is in a later column than Cell, the comments are
+// expected in that column.
+//
+// Header locations start below the cell labeled Header, and the
+// comments are found under the nearest CommentHeader (default
+// "Comment") to its right. Name is a defined name in the workbook.
+// Table locations read every row of an Excel table, with the comments
+// in CommentColumn (default "Comment").
+//
 func NewLocation(spec string, defaultSheet string) (*Location, error) {
 	parts := strings.Split(spec, ":")
 
+	// e.g. A2:A500
+	var end string
+	if n := len(parts); n >= 2 && isRange(parts[n-2], parts[n-1]) {
+		end, parts = parts[n-1], parts[:n-1]
+		spec = strings.Join(parts, ":")
+	}
+
+	var l Location
 	switch len(parts) {
 	case 3:
 		offset, err := strconv.Atoi(parts[0])
 		if err != nil {
 			return nil, err
 		}
-		return &Location{Sheet: parts[1], Axis: parts[2], Offset: offset}, nil
+		l = Location{Sheet: parts[1], Axis: parts[2], Offset: offset}
 	case 2:
-		return &Location{Sheet: parts[0], Axis: parts[1]}, nil
+		l = Location{Sheet: parts[0], Axis: parts[1]}
 	case 1:
+		if m := tableRegexp.FindStringSubmatch(spec); m != nil {
+			l := Location{Table: m[1], Header: m[2], CommentHeader: m[3]}
+			if l.CommentHeader == "" {
+				l.CommentHeader = "Comment"
+			}
+			return &l, nil
+		}
+
+		if nameRegexp.MatchString(spec) && !cellRegexp.MatchString(spec) {
+			return &Location{Name: spec}, nil
+		}
+
 		// e.g. A2
 		if defaultSheet == "" {
 			return nil, fmt.Errorf("cell specification %q requires a default sheet, but none has been defined", spec)
 		}
-		return &Location{Sheet: defaultSheet, Axis: spec}, nil
+		l = Location{Sheet: defaultSheet, Axis: spec}
+	default:
+		return nil, fmt.Errorf("Cell specification %q is invalid. Should be in the form [Sheet:]Cell e.g. Sheet1:A2 or just A2.", spec)
+	}
+
+	if m := headerRegexp.FindStringSubmatch(l.Axis); m != nil {
+		if l.Offset != 0 && m[2] != "" {
+			return nil, fmt.Errorf("cell specification %q cannot have both an offset and a comment header", spec)
+		}
+
+		l.Axis, l.Header, l.CommentHeader = "", m[1], m[2]
+		if l.CommentHeader == "" {
+			l.CommentHeader = "Comment"
+		}
+	}
+
+	if end != "" {
+		col, _, _ := excelize.CellNameToCoordinates(l.Axis)
+		endCol, endRow, _ := excelize.CellNameToCoordinates(end)
+		if endCol > col && l.Offset == 0 {
+			l.Offset = endCol - col
+		}
+		l.End = endRow
+	}
+
+	return &l, nil
+}
+
+// isRange reports whether start:end is a cell range that reads down
+// and to the right, e.g. A2:A500 or A2:B500
+func isRange(start, end string) bool {
+	if !cellRegexp.MatchString(start) || !cellRegexp.MatchString(end) {
+		return false
 	}
 
-	return nil, fmt.Errorf("Cell specification %q is invalid. Should be in the form [Sheet:]Cell e.g. Sheet1:A2 or just A2.", spec)
+	col, row, err := excelize.CellNameToCoordinates(start)
+	if err != nil {
+		return false
+	}
+	endCol, endRow, err := excelize.CellNameToCoordinates(end)
+	if err != nil {
+		return false
+	}
+
+	return endCol >= col && endRow >= row
 }
 
 type Definition struct {
 	Type    Type
 	Id      int
 	Comment string
+	Value   string // optional, see FileConfig.ValueOffset and FormatValue
 }
 
 type File struct {
 	path string
-	xlsx *excelize.File
+	book Workbook
 
 	Config    FileConfig
-	Locations map[Type]*Location
+	Locations map[Type][]*Location
 	Warnings  []string
 }
 
@@ -72,35 +192,37 @@ func newFile(path string, cfg FileConfig) (*File, error) {
 	f := File{path: path, Config: cfg}
 
 	// set locations based on config
-	f.Locations = make(map[Type]*Location)
-	types := []Type{Constant, Numreg, Posreg, Ualm, Ain, Aout, Din, Dout, Gin, Gout, Rin, Rout, Sreg, Flag}
-	for _, t := range types {
-		spec := cfg.SpecFor(t)
-		if spec != "" {
-			loc, err := NewLocation(spec, cfg.Sheet)
-			if err != nil {
-				return nil, err
-			}
-
-			f.Locations[t] = loc
-		}
+	f.Locations, err = cfg.Locations()
+	if err != nil {
+		return nil, err
 	}
 
 	return &f, nil
 }
 
 func OpenFile(path string, cfg FileConfig) (*File, error) {
+	if isManifest(path) {
+		return openManifest(path, cfg)
+	}
+
 	f, err := newFile(path, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	xlsx, err := excelize.OpenFile(f.path)
+	f.book, err = openWorkbook(f.path, cfg.Sheet)
 	if err != nil {
 		return nil, err
 	}
 
-	f.xlsx = xlsx
+	for _, locs := range f.Locations {
+		for _, loc := range locs {
+			err = f.resolve(loc)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	return f, nil
 }
@@ -111,9 +233,18 @@ func NewFile(path string, cfg FileConfig) (*File, error) {
 		return nil, err
 	}
 
+	// there is nothing to look up in a new file
+	for _, locs := range f.Locations {
+		for _, loc := range locs {
+			if !loc.Resolved() {
+				return nil, fmt.Errorf("location %s must be a cell when creating a spreadsheet", loc)
+			}
+		}
+	}
+
 	// file must not exist
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		f.xlsx = excelize.NewFile()
+		f.book = newWorkbook(f.path, cfg.Sheet)
 
 		return f, nil
 	}
@@ -122,26 +253,45 @@ func NewFile(path string, cfg FileConfig) (*File, error) {
 }
 
 func (f *File) Save() error {
-	return f.xlsx.SaveAs(f.path)
+	return f.book.SaveAs(f.path)
 }
 
-func (f *File) readInt(sheet string, col, row int) (int, error) {
-	axis, err := excelize.CoordinatesToCellName(col, row)
+// matches id ranges e.g. 101-116
+var idRangeRegexp = regexp.MustCompile(`^\s*(\d+)\s*-\s*(\d+)\s*$`)
+
+// readIds returns the id in a cell, or every id of a range like 101-116
+// along with ranged set, even if the range has a single id (e.g. 5-5).
+// Ranges cannot go past MaxId.
+func (f *File) readIds(t Type, sheet string, col, row int) (ids []int, ranged bool, err error) {
+	value, err := f.readString(sheet, col, row)
 	if err != nil {
-		return 0, err
+		return nil, false, err
 	}
 
-	value, err := f.xlsx.GetCellValue(sheet, axis)
-	if err != nil {
-		return 0, err
+	if m := idRangeRegexp.FindStringSubmatch(value); m != nil {
+		first, _ := strconv.Atoi(m[1])
+		last, err := strconv.Atoi(m[2])
+
+		// a typo shouldn't expand to billions of ids
+		if err != nil || last > MaxId(t) {
+			return nil, false, newReadError(t, sheet, col, row, value, "%s id range %q goes past %s[%d]", t, value, t, MaxId(t))
+		}
+		if last < first {
+			return nil, false, newReadError(t, sheet, col, row, value, "%s id range %q ends before it starts", t, value)
+		}
+
+		for id := first; id <= last; id++ {
+			ids = append(ids, id)
+		}
+		return ids, true, nil
 	}
 
-	i, err := strconv.Atoi(value)
+	i, err := strconv.Atoi(strings.TrimSpace(value))
 	if err != nil {
-		return 0, err
+		return nil, false, newReadError(t, sheet, col, row, value, "%s id %q is not a number", t, value)
 	}
 
-	return i, nil
+	return []int{i}, false, nil
 }
 
 func (f *File) readString(sheet string, col, row int) (string, error) {
@@ -150,7 +300,7 @@ func (f *File) readString(sheet string, col, row int) (string, error) {
 		return "", err
 	}
 
-	value, err := f.xlsx.GetCellValue(sheet, axis)
+	value, err := f.book.GetCellValue(sheet, axis)
 	if err != nil {
 		return "", err
 	}
@@ -158,84 +308,268 @@ func (f *File) readString(sheet string, col, row int) (string, error) {
 	return value, nil
 }
 
-func (f *File) readDefinition(t Type, sheet string, col, row, offset int) (d Definition, err error) {
-	d.Type = t
+// readPosition reads the position columns that start at col and
+// returns the position formatted by FormatValue, or "" if they are
+// blank
+func (f *File) readPosition(sheet string, col, row int) (string, error) {
+	cells := make([]string, positionColumns)
+	for i := range cells {
+		var err error
+		cells[i], err = f.readString(sheet, col+i, row)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	p, err := positionFromCells(cells)
+	if err != nil {
+		return "", newReadError(Posreg, sheet, col, row, strings.Join(cells, " "), "%s", err)
+	}
+	if p == nil {
+		return "", nil
+	}
+
+	return p.String(), nil
+}
+
+// readDefinitions reads the definitions in a row and whether its id
+// is a range. A range of ids is expanded into a definition per id, with
+// any %d in the comment replaced by the id. R and SR values and PR
+// positions are read when there is a value column.
+func (f *File) readDefinitions(t Type, sheet string, col, row, offset int) (defs []Definition, ranged bool, err error) {
+	ids, ranged, err := f.readIds(t, sheet, col, row)
+	if err != nil {
+		return nil, false, err
+	}
 
-	d.Id, err = f.readInt(sheet, col, row)
+	comment, err := f.readString(sheet, col+offset, row)
 	if err != nil {
-		return
+		return nil, false, err
 	}
 
-	d.Comment, err = f.readString(sheet, col+offset, row)
-	if maxLength := MaxLengthFor(t); len(d.Comment) > maxLength {
-		var axis string
-		axis, err = excelize.CoordinatesToCellName(col+offset, row)
+	var value string
+	if t == Posreg && f.Config.ValueOffset != 0 {
+		value, err = f.readPosition(sheet, col+f.Config.ValueOffset, row)
 		if err != nil {
-			return
+			return nil, false, err
+		}
+	} else if HasValues(t) && f.Config.ValueOffset != 0 {
+		raw, err := f.readString(sheet, col+f.Config.ValueOffset, row)
+		if err != nil {
+			return nil, false, err
+		}
+
+		value, err = FormatValue(t, raw)
+		if err != nil {
+			return nil, false, newReadError(t, sheet, col+f.Config.ValueOffset, row, raw, "%s", err)
+		}
+	}
+
+	for _, id := range ids {
+		d := Definition{Type: t, Id: id, Comment: comment, Value: value}
+		if ranged {
+			d.Comment = strings.Replace(comment, "%d", strconv.Itoa(id), -1)
+		}
+
+		if maxLength := MaxLengthFor(t); len(d.Comment) > maxLength {
+			axis, err := excelize.CoordinatesToCellName(col+offset, row)
+			if err != nil {
+				return nil, false, err
+			}
+
+			f.Warnings = append(f.Warnings, fmt.Sprintf("comment in [%s]%s for %s[%d] will be truncated to %q (length %d > max length %d for %ss)", sheet, axis, d.Type, d.Id, d.Comment[:maxLength], len(d.Comment), maxLength, t))
 		}
 
-		f.Warnings = append(f.Warnings, fmt.Sprintf("comment in [%s]%s for %s[%d] will be truncated to %q (length %d > max length %d for %ss)", sheet, axis, d.Type, d.Id, d.Comment[:maxLength], len(d.Comment), maxLength, t))
+		defs = append(defs, d)
 	}
 
-	return
+	return defs, ranged, nil
 }
 
+// AllDefinitions reads the definitions of every type. The read errors
+// of every type are returned together.
 func (f *File) AllDefinitions() (map[Type][]Definition, error) {
 	defs := make(map[Type][]Definition)
 
-	for t, _ := range f.Locations {
-		if t == Constant {
-			continue
+	var types []Type
+	for t := range f.Locations {
+		if t != Constant {
+			types = append(types, t)
 		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
 
+	var errs ReadErrorList
+	for _, t := range types {
 		d, err := f.Definitions(t)
-		if err != nil {
+		if err = errs.collect(err); err != nil {
 			return nil, err
 		}
 
 		defs[t] = d
 	}
 
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
 	return defs, nil
 }
 
 func (f *File) Definitions(t Type) ([]Definition, error) {
-	loc, defined := f.Locations[t]
+	defs, _, err := f.definitions(t)
+	return defs, err
+}
+
+// offsetFor returns the column offset between ids and comments at the
+// provided location
+func (f *File) offsetFor(loc *Location) int {
+	if loc.Offset != 0 {
+		return loc.Offset
+	}
+	return f.Config.Offset
+}
+
+// A cell is where a definition was found in the spreadsheet
+type cell struct {
+	loc    *Location
+	row    int
+	ranged bool // the row defines a range of ids
+}
+
+func (c cell) String() string {
+	col, _, _ := excelize.CellNameToCoordinates(c.loc.Axis)
+	axis, _ := excelize.CoordinatesToCellName(col, c.row)
+	return fmt.Sprintf("[%s]%s", c.loc.Sheet, axis)
+}
+
+// definitions merges the definitions found at each location for t and
+// also returns the cell each one was found in. An id that is defined at
+// more than one location is an error.
+func (f *File) definitions(t Type) (defs []Definition, cells []cell, err error) {
+	defs, cells, err = f.readableDefinitions(t)
+
+	var errs ReadErrorList
+	if err = errs.collect(err); err != nil {
+		return nil, nil, err
+	}
+
+	// duplicates within a single location are left alone
+	first := make(map[int]int)
+	for i, d := range defs {
+		j, seen := first[d.Id]
+		if !seen {
+			first[d.Id] = i
+			continue
+		}
+
+		if cells[j].loc != cells[i].loc {
+			col, _, _ := excelize.CellNameToCoordinates(cells[i].loc.Axis)
+			errs.Add(newReadError(t, cells[i].loc.Sheet, col, cells[i].row, strconv.Itoa(d.Id), "%s[%d] is already defined in %s", t, d.Id, cells[j]))
+		}
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return defs, cells, nil
+}
+
+// readableDefinitions reads the definitions at every location for t
+// like definitions, but without checking for duplicates. The rows that
+// cannot be read are returned in a ReadErrorList along with the
+// definitions of the rows that can.
+func (f *File) readableDefinitions(t Type) (defs []Definition, cells []cell, err error) {
+	locs, defined := f.Locations[t]
 	if !defined {
-		return nil, fmt.Errorf("Location for %s not defined", t)
+		return nil, nil, fmt.Errorf("Location for %s not defined", t)
+	}
+
+	var errs ReadErrorList
+	for _, loc := range locs {
+		d, c, err := f.definitionsAt(t, loc)
+		if err = errs.collect(err); err != nil {
+			return nil, nil, err
+		}
+
+		defs = append(defs, d...)
+		cells = append(cells, c...)
+	}
+
+	return defs, cells, errs.Err()
+}
+
+// definitionsAt reads the definitions at a single location. The rows
+// that can't be read are returned in a ReadErrorList along with the
+// definitions of the rows that can.
+func (f *File) definitionsAt(t Type, loc *Location) (defs []Definition, cells []cell, err error) {
+	var errs ReadErrorList
+	err = f.eachRow(t, loc, func(col, row int, id string) error {
+		d, ranged, err := f.readDefinitions(t, loc.Sheet, col, row, f.offsetFor(loc))
+		if err != nil {
+			return errs.collect(err)
+		}
+
+		for range d {
+			cells = append(cells, cell{loc: loc, row: row, ranged: ranged})
+		}
+		defs = append(defs, d...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
+	return defs, cells, errs.Err()
+}
+
+// eachRow calls fn with every non-blank id at the location until it
+// ends. Blank rows that are skipped along the way are warned about.
+func (f *File) eachRow(t Type, loc *Location, fn func(col, row int, id string) error) error {
 	col, row, err := excelize.CellNameToCoordinates(loc.Axis)
 	if err != nil {
-		return nil, fmt.Errorf("Invalid location for %s: %q", t, loc.Axis)
+		return fmt.Errorf("Invalid location for %s: %q", t, loc.Axis)
 	}
 
-	var defs []Definition
-	for ; ; row++ {
+	end := loc.End
+	if end == 0 && loc.Blanks == 0 {
+		rows, err := f.book.GetRows(loc.Sheet)
+		if err != nil {
+			return err
+		}
+		end = len(rows)
+	}
+
+	blanks := 0
+	for ; (end == 0 && loc.Blanks > 0) || row <= end; row++ {
 		// check for blank id
-		s, err := f.readString(loc.Sheet, col, row)
+		id, err := f.readString(loc.Sheet, col, row)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		if s == "" {
-			break
+		if id == "" {
+			blanks++
+			if loc.Blanks > 0 && blanks >= loc.Blanks {
+				break
+			}
+			continue
 		}
 
-		offset := loc.Offset
-		if offset == 0 {
-			offset = f.Config.Offset
+		if blanks > 0 {
+			from, _ := excelize.CoordinatesToCellName(col, row-blanks)
+			to, _ := excelize.CoordinatesToCellName(col, row-1)
+			f.Warnings = append(f.Warnings, fmt.Sprintf("skipped %d blank %s in [%s]%s:%s between %ss", blanks, Pluralize("row", blanks), loc.Sheet, from, to, t))
+			blanks = 0
 		}
 
-		d, err := f.readDefinition(t, loc.Sheet, col, row, offset)
+		err = fn(col, row, id)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		defs = append(defs, d)
-
 	}
 
-	return defs, nil
+	return nil
 }
 
 func (f *File) SetValue(sheet string, col int, row int, value interface{}) error {
@@ -244,52 +578,67 @@ func (f *File) SetValue(sheet string, col int, row int, value interface{}) error
 		return err
 	}
 
-	return f.xlsx.SetCellValue(sheet, axis, value)
+	return f.book.SetCellValue(sheet, axis, value)
+}
+
+// setComment updates the comment next to the id in c
+func (f *File) setComment(c cell, comment string) error {
+	col, _, err := excelize.CellNameToCoordinates(c.loc.Axis)
+	if err != nil {
+		return err
+	}
+
+	return f.SetValue(c.loc.Sheet, col+f.offsetFor(c.loc), c.row, comment)
 }
 
-// excelize does not create a new sheet if it already exists
-func (f *File) CreateSheet(name string) {
-	f.xlsx.NewSheet(name)
+// CreateSheet creates a sheet if it doesn't exist yet. It returns an
+// error if the sheet exists but cannot be read, e.g. a malformed CSV
+// file.
+func (f *File) CreateSheet(name string) error {
+	// excelize does not create a new sheet if it already exists
+	f.book.NewSheet(name)
+
+	_, err := f.book.GetRows(name)
+	return err
 }
 
 func (f *File) Constants() (map[string]string, error) {
-	loc, defined := f.Locations[Constant]
+	locs, defined := f.Locations[Constant]
 	if !defined {
 		return nil, fmt.Errorf("Location for %s not defined", Constant)
 	}
 
 	constants := make(map[string]string)
+	found := make(map[string]*Location)
+
+	var errs ReadErrorList
+	for _, loc := range locs {
+		err := f.eachRow(Constant, loc, func(col, row int, id string) error {
+			if l, seen := found[id]; seen && l != loc {
+				errs.Add(newReadError(Constant, loc.Sheet, col, row, id, "constant %q is already defined in %s", id, l))
+				return nil
+			}
+			found[id] = loc
 
-	col, row, err := excelize.CellNameToCoordinates(loc.Axis)
-	if err != nil {
-		return nil, fmt.Errorf("Invalid location for %s: %q", Constant, loc.Axis)
-	}
-
-	for ; ; row++ {
-		// check for blank identifier
-		id, err := f.readString(loc.Sheet, col, row)
-		if err != nil {
-			return nil, err
-		}
-		if id == "" {
-			break
-		}
-
-		offset := loc.Offset
-		if offset == 0 {
-			offset = f.Config.Offset
-		}
+			value, err := f.readString(loc.Sheet, col+f.offsetFor(loc), row)
+			if err != nil {
+				return err
+			}
+			if value == "" {
+				f.Warnings = append(f.Warnings, fmt.Sprintf("Definition for constant %q is blank", id))
+				return nil
+			}
 
-		value, err := f.readString(loc.Sheet, col+offset, row)
+			constants[id] = value
+			return nil
+		})
 		if err != nil {
 			return nil, err
 		}
-		if value == "" {
-			f.Warnings = append(f.Warnings, fmt.Sprintf("Definition for constant %q is blank", id))
-			continue
-		}
+	}
 
-		constants[id] = value
+	if err := errs.Err(); err != nil {
+		return nil, err
 	}
 
 	return constants, nil
@@ -1,26 +1,33 @@
 package fexcel
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
 )
 
 const testDir = "testdata"
 
 func TestOpenFile(t *testing.T) {
 	fpath := filepath.Join(testDir, "test.xlsx")
-	cfg := FileConfig{Offset: 1, Numregs: "Data:A2"}
+	cfg := FileConfig{Offset: 1, Numregs: []string{"Data:A2"}}
 
 	f, err := OpenFile(fpath, cfg)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if f.Locations[Numreg].Sheet != "Data" {
-		t.Errorf("Bad sheet. Got %q, want %q", f.Locations[Numreg].Sheet, "Data")
+	if len(f.Locations[Numreg]) != 1 {
+		t.Fatalf("Bad locations. Got %d, want %d", len(f.Locations[Numreg]), 1)
+	}
+	if f.Locations[Numreg][0].Sheet != "Data" {
+		t.Errorf("Bad sheet. Got %q, want %q", f.Locations[Numreg][0].Sheet, "Data")
 	}
-	if f.Locations[Numreg].Axis != "A2" {
-		t.Errorf("Bad axis. Got %q, want %q", f.Locations[Numreg].Axis, "A2")
+	if f.Locations[Numreg][0].Axis != "A2" {
+		t.Errorf("Bad axis. Got %q, want %q", f.Locations[Numreg][0].Axis, "A2")
 	}
 }
 
@@ -30,7 +37,7 @@ func TestConstants(t *testing.T) {
 	f, err := OpenFile(fpath, FileConfig{
 		Sheet:     "Data",
 		Offset:    1,
-		Constants: "M2",
+		Constants: []string{"M2"},
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -56,20 +63,20 @@ func TestDefinitions(t *testing.T) {
 	cfg := FileConfig{
 		Sheet:     "Data",
 		Offset:    1,
-		Constants: "M2",
-		Numregs:   "A2",
-		Posregs:   "D2",
-		Sregs:     "G2",
-		Flags:     "J2",
-		Dins:      "IO:A2",
-		Douts:     "IO:C2",
-		Rins:      "IO:E2",
-		Routs:     "IO:G2",
-		Gins:      "IO:I2",
-		Gouts:     "IO:K2",
-		Ains:      "IO:M2",
-		Aouts:     "IO:O2",
-		Ualms:     "Alarms:A2",
+		Constants: []string{"M2"},
+		Numregs:   []string{"A2"},
+		Posregs:   []string{"D2"},
+		Sregs:     []string{"G2"},
+		Flags:     []string{"J2"},
+		Dins:      []string{"IO:A2"},
+		Douts:     []string{"IO:C2"},
+		Rins:      []string{"IO:E2"},
+		Routs:     []string{"IO:G2"},
+		Gins:      []string{"IO:I2"},
+		Gouts:     []string{"IO:K2"},
+		Ains:      []string{"IO:M2"},
+		Aouts:     []string{"IO:O2"},
+		Ualms:     []string{"Alarms:A2"},
 	}
 
 	f, err := OpenFile(fpath, cfg)
@@ -84,91 +91,91 @@ func TestDefinitions(t *testing.T) {
 		{
 			Numreg,
 			[]Definition{
-				{Numreg, 1, "this is an extremely long comment"},
-				{Numreg, 2, "two"},
-				{Numreg, 3, "three"},
-				{Numreg, 4, "four"},
-				{Numreg, 5, "five"},
+				{Numreg, 1, "this is an extremely long comment", ""},
+				{Numreg, 2, "two", ""},
+				{Numreg, 3, "three", ""},
+				{Numreg, 4, "four", ""},
+				{Numreg, 5, "five", ""},
 			},
 		},
 		{
 			Posreg,
 			[]Definition{
-				{Posreg, 1, "pr1"},
-				{Posreg, 2, "pr2"},
-				{Posreg, 3, "pr3"},
-				{Posreg, 4, "pr4"},
-				{Posreg, 5, "pr5"},
+				{Posreg, 1, "pr1", ""},
+				{Posreg, 2, "pr2", ""},
+				{Posreg, 3, "pr3", ""},
+				{Posreg, 4, "pr4", ""},
+				{Posreg, 5, "pr5", ""},
 			},
 		},
 		{
 			Sreg,
 			[]Definition{
-				{Sreg, 1, "sreg1"},
-				{Sreg, 2, "sreg2"},
+				{Sreg, 1, "sreg1", ""},
+				{Sreg, 2, "sreg2", ""},
 			},
 		},
 		{
 			Din,
 			[]Definition{
-				{Din, 1, "din1"},
-				{Din, 2, "din2"},
-				{Din, 3, "din3"},
+				{Din, 1, "din1", ""},
+				{Din, 2, "din2", ""},
+				{Din, 3, "din3", ""},
 			},
 		},
 		{
 			Dout,
 			[]Definition{
-				{Dout, 1, "dout1"},
-				{Dout, 2, "dout2"},
-				{Dout, 3, "dout3"},
-				{Dout, 4, "dout4"},
+				{Dout, 1, "dout1", ""},
+				{Dout, 2, "dout2", ""},
+				{Dout, 3, "dout3", ""},
+				{Dout, 4, "dout4", ""},
 			},
 		},
 		{
 			Rin,
 			[]Definition{
-				{Rin, 1, "rin1"},
-				{Rin, 2, "rin2"},
+				{Rin, 1, "rin1", ""},
+				{Rin, 2, "rin2", ""},
 			},
 		},
 		{
 			Rout,
 			[]Definition{
-				{Rout, 1, "rout1"},
+				{Rout, 1, "rout1", ""},
 			},
 		},
 		{
 			Gin,
 			[]Definition{
-				{Gin, 1, "gin1"},
+				{Gin, 1, "gin1", ""},
 			},
 		},
 		{
 			Gout,
 			[]Definition{
-				{Gout, 1, "gout1"},
+				{Gout, 1, "gout1", ""},
 			},
 		},
 		{
 			Ain,
 			[]Definition{
-				{Ain, 1, "ain1"},
+				{Ain, 1, "ain1", ""},
 			},
 		},
 		{
 			Aout,
 			[]Definition{
-				{Aout, 1, "aout1"},
+				{Aout, 1, "aout1", ""},
 			},
 		},
 		{
 			Ualm,
 			[]Definition{
-				{Ualm, 1, "test"},
-				{Ualm, 2, "test two"},
-				{Ualm, 3, "test three"},
-				{Ualm, 4, "test four"},
+				{Ualm, 1, "test", ""},
+				{Ualm, 2, "test two", ""},
+				{Ualm, 3, "test three", ""},
+				{Ualm, 4, "test four", ""},
 			},
 		},
 	}
@@ -223,6 +230,13 @@ func TestNewLocation(t *testing.T) {
 		{"Bar:A2", "Foo", "A2", "Bar", 0},
 		{"D2", "Baz", "D2", "Baz", 0},
 		{"5:Bar:A2", "Foo", "A2", "Bar", 5},
+		{"A2:A500", "Foo", "A2", "Foo", 0},
+		{"Bar:A2:C500", "Foo", "A2", "Bar", 2},
+		{"IO2:A2", "Foo", "A2", "IO2", 0}, // not a range
+		{"Bar:[DI]", "Foo", "", "Bar", 0},
+		{"[DI][Description]", "Foo", "", "Foo", 0},
+		{"DI_LIST", "", "", "", 0},
+		{"DiTable[DI][Comment]", "", "", "", 0},
 	}
 
 	for _, test := range tests {
@@ -231,6 +245,10 @@ func TestNewLocation(t *testing.T) {
 			t.Fatal(err)
 		}
 
+		if l.Offset != test.expOffset {
+			t.Errorf("Bad offset. Got %d, want %d", l.Offset, test.expOffset)
+		}
+
 		if l.Axis != test.expAxis {
 			t.Errorf("Bad axis. Got %q, want %q", l.Axis, test.expAxis)
 		}
@@ -240,9 +258,446 @@ func TestNewLocation(t *testing.T) {
 	}
 }
 
+func TestNewLocationLookups(t *testing.T) {
+	l, err := NewLocation("IO:[DI][Description]", "Foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Header != "DI" || l.CommentHeader != "Description" {
+		t.Errorf("Bad headers. Got %q and %q, want %q and %q", l.Header, l.CommentHeader, "DI", "Description")
+	}
+
+	l, err = NewLocation("IO:[DI]", "Foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.CommentHeader != "Comment" {
+		t.Errorf("Bad comment header. Got %q, want %q", l.CommentHeader, "Comment")
+	}
+
+	l, err = NewLocation("DI_LIST", "Foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Name != "DI_LIST" {
+		t.Errorf("Bad name. Got %q, want %q", l.Name, "DI_LIST")
+	}
+
+	_, err = NewLocation("2:IO:[DI][Description]", "Foo")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestResolveLocations(t *testing.T) {
+	dir, fpath := tempSpreadsheet(t)
+	defer os.RemoveAll(dir)
+
+	f, err := OpenFile(fpath, FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.book.(*excelize.File).SetDefinedName(&excelize.DefinedName{Name: "SREG_LIST", RefersTo: "Data!$G$2:$H$3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := FileConfig{
+		Sheet:     "Data",
+		Offset:    5, // overridden by each location
+		Numregs:   []string{"[numregs]"},
+		Posregs:   []string{"Data:[POSREGS]"},
+		Sregs:     []string{"SREG_LIST"},
+		Constants: []string{"1:Data:[constants]"},
+		Dins:      []string{"IO:[di][do]"},
+	}
+
+	f, err = OpenFile(fpath, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[Type]string{
+		Numreg:   "1:Data:A2",
+		Posreg:   "1:Data:D2",
+		Sreg:     "1:Data:G2",
+		Constant: "1:Data:M2",
+		Din:      "2:IO:A2",
+	}
+	for typ, want := range expected {
+		if got := f.Locations[typ][0].String(); got != want {
+			t.Errorf("Bad %s location. Got %q, want %q", typ, got, want)
+		}
+	}
+
+	defs, err := f.Definitions(Sreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 2 || defs[1].Comment != "sreg2" {
+		t.Errorf("Bad sreg definitions: %v", defs)
+	}
+
+	errors := []struct {
+		spec string
+		want string
+	}{
+		{"IO:[di]", `header "Comment" not found to the right of "di" on sheet "IO"`},
+		{"IO:[foo]", `header "foo" not found on sheet "IO"`},
+		{"FOO_LIST", `defined name "FOO_LIST" not found`},
+	}
+
+	for _, test := range errors {
+		_, err := OpenFile(fpath, FileConfig{Offset: 1, Dins: []string{test.spec}})
+		if err == nil {
+			t.Errorf("expected an error for %q", test.spec)
+			continue
+		}
+		if err.Error() != test.want {
+			t.Errorf("Bad error. Got %q, want %q", err.Error(), test.want)
+		}
+	}
+}
+
+func TestDefinitionsUntil(t *testing.T) {
+	dir, fpath := tempSpreadsheet(t)
+	defer os.RemoveAll(dir)
+
+	f, err := OpenFile(fpath, FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ids 1-5 are in A2:A6
+	f.SetValue("Data", 1, 8, 8)
+	f.SetValue("Data", 2, 8, "eight")
+	f.SetValue("Data", 1, 10, 10)
+	f.SetValue("Data", 2, 10, "ten")
+	err = f.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		until    string
+		spec     string
+		ids      []int
+		warnings []string
+	}{
+		{"", "A2", []int{1, 2, 3, 4, 5}, nil},
+		{"blank", "A2", []int{1, 2, 3, 4, 5}, nil},
+		{"2", "A2", []int{1, 2, 3, 4, 5, 8, 10}, []string{
+			"skipped 1 blank row in [Data]A7:A7 between Rs",
+			"skipped 1 blank row in [Data]A9:A9 between Rs",
+		}},
+		{"used", "A2", []int{1, 2, 3, 4, 5, 8, 10}, []string{
+			"skipped 1 blank row in [Data]A7:A7 between Rs",
+			"skipped 1 blank row in [Data]A9:A9 between Rs",
+		}},
+		{"", "A2:A9", []int{1, 2, 3, 4, 5, 8}, []string{
+			"skipped 1 blank row in [Data]A7:A7 between Rs",
+		}},
+	}
+
+	for _, test := range tests {
+		f, err := OpenFile(fpath, FileConfig{Sheet: "Data", Offset: 1, Until: test.until, Numregs: []string{test.spec}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defs, err := f.Definitions(Numreg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []int
+		for _, d := range defs {
+			ids = append(ids, d.Id)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(test.ids) {
+			t.Errorf("%q %q: Bad ids. Got %v, want %v", test.until, test.spec, ids, test.ids)
+		}
+
+		// the long comment in A2 is always warned about
+		warnings := f.Warnings[1:]
+		if fmt.Sprint(warnings) != fmt.Sprint(test.warnings) {
+			t.Errorf("%q %q: Bad warnings. Got %q, want %q", test.until, test.spec, warnings, test.warnings)
+		}
+	}
+
+	_, err = OpenFile(fpath, FileConfig{Sheet: "Data", Offset: 1, Until: "never", Numregs: []string{"A2"}})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestDefinitionsIdRanges(t *testing.T) {
+	dir, fpath := tempSpreadsheet(t)
+	defer os.RemoveAll(dir)
+
+	f, err := OpenFile(fpath, FileConfig{Sheet: "Ranges", Offset: 1, Dins: []string{"A2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.CreateSheet("Ranges")
+	f.SetValue("Ranges", 1, 2, "101-103")
+	f.SetValue("Ranges", 2, 2, "Gripper %d closed")
+	f.SetValue("Ranges", 1, 3, "5")
+	f.SetValue("Ranges", 2, 3, "Spare %d")
+	f.SetValue("Ranges", 1, 4, "7 - 8")
+	f.SetValue("Ranges", 2, 4, "Clamp")
+	f.SetValue("Ranges", 1, 5, "9-9")
+	f.SetValue("Ranges", 2, 5, "Gripper %d open")
+
+	defs, err := f.Definitions(Din)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Definition{
+		{Din, 101, "Gripper 101 closed", ""},
+		{Din, 102, "Gripper 102 closed", ""},
+		{Din, 103, "Gripper 103 closed", ""},
+		{Din, 5, "Spare %d", ""},
+		{Din, 7, "Clamp", ""},
+		{Din, 8, "Clamp", ""},
+		{Din, 9, "Gripper 9 open", ""},
+	}
+
+	if len(defs) != len(expected) {
+		t.Fatalf("Bad length. Got %d, want %d", len(defs), len(expected))
+	}
+	for i, def := range defs {
+		if def != expected[i] {
+			t.Errorf("Bad definition. Got %v, want %v", def, expected[i])
+		}
+	}
+
+	_, cells, err := f.definitions(Din)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range cells {
+		if want := i != 3; c.ranged != want {
+			t.Errorf("cells[%d].ranged: Got %t, want %t", i, c.ranged, want)
+		}
+	}
+
+	f.SetValue("Ranges", 1, 5, "12-10")
+
+	_, err = f.Definitions(Din)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	want := `[Ranges]A5: DI id range "12-10" ends before it starts`
+	if err.Error() != want {
+		t.Errorf("Bad error. Got %q, want %q", err.Error(), want)
+	}
+
+	// typos are not expanded
+	for _, value := range []string{"101-1000000000", "1-99999999999999999999"} {
+		f.SetValue("Ranges", 1, 5, value)
+
+		_, err = f.Definitions(Din)
+		want = fmt.Sprintf(`[Ranges]A5: DI id range %q goes past DI[9999]`, value)
+		if err == nil || err.Error() != want {
+			t.Errorf("Bad error. Got %v, want %q", err, want)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	dir, fpath := tempSpreadsheet(t)
+	defer os.RemoveAll(dir)
+
+	f, err := OpenFile(fpath, FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2"}, Dins: []string{"IO:A2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.SetValue("Data", 1, 3, "R2")
+	f.SetValue("Data", 1, 5, "four")
+	f.SetValue("IO", 1, 2, "DI5")
+
+	_, err = f.AllDefinitions()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	errs, ok := err.(ReadErrorList)
+	if !ok {
+		t.Fatalf("Bad error type. Got %T, want ReadErrorList", err)
+	}
+
+	expected := []ReadError{
+		{Sheet: "Data", Axis: "A3", Type: Numreg, Value: "R2", Msg: `R id "R2" is not a number`},
+		{Sheet: "Data", Axis: "A5", Type: Numreg, Value: "four", Msg: `R id "four" is not a number`},
+		{Sheet: "IO", Axis: "A2", Type: Din, Value: "DI5", Msg: `DI id "DI5" is not a number`},
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Bad length. Got %d, want %d", len(errs), len(expected))
+	}
+	for i, e := range errs {
+		if *e != expected[i] {
+			t.Errorf("Bad error. Got %+v, want %+v", *e, expected[i])
+		}
+	}
+
+	want := `3 errors reading spreadsheet:
+  [Data]A3: R id "R2" is not a number
+  [Data]A5: R id "four" is not a number
+  [IO]A2: DI id "DI5" is not a number`
+	if err.Error() != want {
+		t.Errorf("Bad error. Got %q, want %q", err.Error(), want)
+	}
+}
+
+func TestTableDefinitions(t *testing.T) {
+	dir, fpath := tempSpreadsheet(t)
+	defer os.RemoveAll(dir)
+
+	f, err := OpenFile(fpath, FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.CreateSheet("Tables")
+	values := [][]interface{}{
+		{"Comment", "DI"},
+		{"one", 1},
+		{"", ""},
+		{"three", 3},
+		{"outside", 4},
+	}
+	for i, row := range values {
+		for j, v := range row {
+			f.SetValue("Tables", j+2, i+1, v)
+		}
+	}
+	err = f.book.(*excelize.File).AddTable("Tables", "B1", "C4", `{"table_name":"DiTable"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = OpenFile(fpath, FileConfig{Offset: 1, Dins: []string{"ditable[DI]"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := f.Locations[Din][0].String(), "-1:Tables:C2"; got != want {
+		t.Errorf("Bad location. Got %q, want %q", got, want)
+	}
+
+	defs, err := f.Definitions(Din)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Definition{
+		{Din, 1, "one", ""},
+		{Din, 3, "three", ""},
+	}
+
+	if len(defs) != len(expected) {
+		t.Fatalf("Bad length. Got %d, want %d", len(defs), len(expected))
+	}
+	for i, def := range defs {
+		if def != expected[i] {
+			t.Errorf("Bad definition. Got %v, want %v", def, expected[i])
+		}
+	}
+
+	errors := []struct {
+		spec string
+		want string
+	}{
+		{"Nope[DI]", `table "Nope" not found`},
+		{"DiTable[DO]", `column "DO" not found in table "DiTable"`},
+		{"DiTable[DI][Description]", `column "Description" not found in table "DiTable"`},
+	}
+
+	for _, test := range errors {
+		_, err := OpenFile(fpath, FileConfig{Offset: 1, Dins: []string{test.spec}})
+		if err == nil {
+			t.Errorf("expected an error for %q", test.spec)
+			continue
+		}
+		if err.Error() != test.want {
+			t.Errorf("Bad error. Got %q, want %q", err.Error(), test.want)
+		}
+	}
+}
+
+func TestDefinitionsMultipleLocations(t *testing.T) {
+	dir, fpath := tempSpreadsheet(t)
+	defer os.RemoveAll(dir)
+
+	cfg := FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2", "More:B3"}}
+
+	f, err := OpenFile(fpath, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.CreateSheet("More")
+	f.SetValue("More", 2, 3, 10)
+	f.SetValue("More", 3, 3, "ten")
+	f.SetValue("More", 2, 4, 11)
+	f.SetValue("More", 3, 4, "eleven")
+
+	defs, err := f.Definitions(Numreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Definition{
+		{Numreg, 1, "this is an extremely long comment", ""},
+		{Numreg, 2, "two", ""},
+		{Numreg, 3, "three", ""},
+		{Numreg, 4, "four", ""},
+		{Numreg, 5, "five", ""},
+		{Numreg, 10, "ten", ""},
+		{Numreg, 11, "eleven", ""},
+	}
+
+	if len(defs) != len(expected) {
+		t.Fatalf("Bad length. Got %d, want %d", len(defs), len(expected))
+	}
+	for i, def := range defs {
+		if def != expected[i] {
+			t.Errorf("Bad definition. Got %v, want %v", def, expected[i])
+		}
+	}
+
+	// duplicate id across locations
+	f.SetValue("More", 2, 5, 3)
+	f.SetValue("More", 3, 5, "three again")
+
+	_, err = f.Definitions(Numreg)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	want := "[More]B5: R[3] is already defined in [Data]A4"
+	if err.Error() != want {
+		t.Errorf("Bad error. Got %q, want %q", err.Error(), want)
+	}
+}
+
 func TestNewFile(t *testing.T) {
 	fpath := filepath.Join(testDir, "newfile.xlsx")
-	cfg := FileConfig{Offset: 1, Numregs: "Data:A2"}
+	cfg := FileConfig{Offset: 1, Numregs: []string{"Data:A2"}}
 
 	_, err := NewFile(fpath, cfg)
 	if err != nil {
@@ -252,7 +707,7 @@ func TestNewFile(t *testing.T) {
 
 func TestNewFileAlreadyExists(t *testing.T) {
 	fpath := filepath.Join(testDir, "test.xlsx")
-	cfg := FileConfig{Offset: 1, Numregs: "Data:A2"}
+	cfg := FileConfig{Offset: 1, Numregs: []string{"Data:A2"}}
 
 	_, err := NewFile(fpath, cfg)
 	if err == nil {
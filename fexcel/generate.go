@@ -0,0 +1,214 @@
+package fexcel
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// array sizes of a default controller. Larger arrays are generated
+// when the spreadsheet defines higher ids.
+var defaultCount = map[Type]int{
+	Numreg: 200,
+	Posreg: 100,
+	Sreg:   25,
+	Ualm:   10,
+}
+
+// ioTypes in the order they are listed in iostate.dg
+var ioTypes = []Type{Ain, Aout, Gin, Gout, Din, Dout, Rin, Rout, Flag}
+
+type Generator struct {
+	file *File
+	dir  string
+
+	Definitions map[Type][]Definition
+}
+
+// NewGenerator writes the definitions of the spreadsheet at path to
+// numreg.va, posreg.va, strreg.va, sysvars.va and iostate.dg in dir.
+// The generated directory can be used as a target.
+func NewGenerator(path string, cfg Config, dir string) (*Generator, error) {
+	f, err := OpenFile(path, cfg.FileConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	defs, err := f.AllDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Generator{file: f, dir: dir, Definitions: defs}, nil
+}
+
+func (g *Generator) Generate(w io.Writer) error {
+	err := os.MkdirAll(g.dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	// every IO type is written to iostate.dg
+	files := make(map[string][]Type)
+	var filenames []string
+	for t := range g.Definitions {
+		name := MDFile(t)
+		if name == "" {
+			return fmt.Errorf("cannot generate %ss", t)
+		}
+		if _, ok := files[name]; !ok {
+			filenames = append(filenames, name)
+		}
+		files[name] = append(files[name], t)
+	}
+	sort.Strings(filenames)
+
+	for _, name := range filenames {
+		var src string
+		if name == MDFile(Din) {
+			src, err = generateIO(g.Definitions)
+		} else {
+			t := files[name][0]
+			src, err = generateVA(t, g.Definitions[t])
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "Writing %s\n", filepath.Join(g.dir, name))
+		err = ioutil.WriteFile(filepath.Join(g.dir, name), []byte(src), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *Generator) Warnings() []string {
+	return g.file.Warnings
+}
+
+// storedComments returns the comments of defs by id as a controller would
+// store them, along with the highest id
+func storedComments(t Type, defs []Definition) (map[int]string, int, error) {
+	c := make(map[int]string)
+	max := defaultCount[t]
+
+	for _, d := range defs {
+		if d.Id < 1 {
+			return nil, 0, fmt.Errorf("%s[%d] is out of range", t, d.Id)
+		}
+
+		c[d.Id] = Truncated(d.Comment, t)
+		if d.Id > max {
+			max = d.Id
+		}
+	}
+
+	return c, max, nil
+}
+
+// generateVA returns the contents of MDFile(t) for a register or
+// user alarm type. R and SR values and PR positions are written when
+// defined.
+func generateVA(t Type, defs []Definition) (string, error) {
+	c, count, err := storedComments(t, defs)
+	if err != nil {
+		return "", err
+	}
+
+	for id, comment := range c {
+		if strings.Contains(comment, "'") {
+			return "", fmt.Errorf("comment %q for %s[%d] cannot contain a single quote", comment, t, id)
+		}
+	}
+
+	values := make(map[int]string)
+	for _, d := range defs {
+		if strings.Contains(d.Value, "'") {
+			return "", fmt.Errorf("value %q for %s[%d] cannot contain a single quote", d.Value, t, d.Id)
+		}
+		values[d.Id] = d.Value
+	}
+
+	var b strings.Builder
+	switch t {
+	case Numreg:
+		fmt.Fprintf(&b, "[*NUMREG*]$NUMREG  Storage: SHADOW  Access: RW  : ARRAY[%d] OF Numeric Reg\n", count)
+		for id := 1; id <= count; id++ {
+			value := values[id]
+			if value == "" {
+				value = "0"
+			}
+			fmt.Fprintf(&b, "  [%d] = %s  '%s' \n", id, value, c[id])
+		}
+	case Posreg:
+		fmt.Fprintf(&b, "[*POSREG*]$POSREG  Storage: SHADOW  Access: RW  : ARRAY[1,%d] OF Position Reg\n", count)
+		for id := 1; id <= count; id++ {
+			p, err := ParsePosition(values[id])
+			if err != nil {
+				return "", fmt.Errorf("%s[%d]: %s", t, id, err)
+			}
+			fmt.Fprintf(&b, "    [1,%d] =   '%s'%s", id, c[id], positionBody(p, "\n"))
+		}
+	case Sreg:
+		fmt.Fprintf(&b, "[*STRREG*]$STRREG  Storage: SHADOW  Access: RW  : ARRAY[%d] OF String Reg\n", count)
+		for id := 1; id <= count; id++ {
+			fmt.Fprintf(&b, "  [%d] = '%s'  '%s' \n", id, values[id], c[id])
+		}
+	case Ualm:
+		fmt.Fprintf(&b, "[*SYSTEM*]%s  Storage: SHADOW  Access: RW  : ARRAY[%d] OF STRING[%d]\n", ualmMsgHeader, count, MaxLengthFor(Ualm))
+		for id := 1; id <= count; id++ {
+			if comment, ok := c[id]; ok {
+				fmt.Fprintf(&b, "  [%d] = '%s'\n", id, comment)
+			} else {
+				fmt.Fprintf(&b, "  [%d] = Uninitialized\n", id)
+			}
+		}
+	default:
+		return "", fmt.Errorf("cannot generate %s for %ss", MDFile(t), t)
+	}
+
+	return b.String(), nil
+}
+
+// generateIO returns the contents of iostate.dg for the IO types in
+// defs. Only the defined ports are listed.
+func generateIO(defs map[Type][]Definition) (string, error) {
+	var b strings.Builder
+	b.WriteString("IO STATUS::\n\n")
+
+	for _, t := range ioTypes {
+		if _, ok := defs[t]; !ok {
+			continue
+		}
+
+		c, _, err := storedComments(t, defs[t])
+		if err != nil {
+			return "", err
+		}
+
+		var ids []int
+		for id := range c {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		value := "OFF"
+		switch t {
+		case Ain, Aout, Gin, Gout:
+			value = "   0"
+		}
+
+		for _, id := range ids {
+			fmt.Fprintf(&b, "%s[%4d] %s  %s\n", ioPrefix[t], id, value, c[id])
+		}
+	}
+
+	return b.String(), nil
+}
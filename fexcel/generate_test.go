@@ -0,0 +1,113 @@
+package fexcel
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fexcel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := Config{FileConfig: FileConfig{
+		Sheet:   "IO",
+		Offset:  1,
+		Numregs: []string{"Data:A2"},
+		Posregs: []string{"Data:D2"},
+		Sregs:   []string{"Data:G2"},
+		Flags:   []string{"Data:J2"},
+		Ualms:   []string{"Alarms:A2"},
+		Dins:    []string{"A2"},
+		Douts:   []string{"C2"},
+		Rins:    []string{"E2"},
+		Gins:    []string{"I2"},
+		Aouts:   []string{"O2"},
+	}}
+
+	g, err := NewGenerator("testdata/test.xlsx", cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = g.Generate(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the generated directory is a target with the spreadsheet's comments
+	d, err := NewDiffCommand("testdata/test.xlsx", cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, typ := range []Type{Numreg, Posreg, Sreg, Flag, Ualm, Din, Dout, Rin, Gin, Aout} {
+		comparisons, err := d.Compare(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comparisons) == 0 {
+			t.Errorf("No %s comparisons", typ)
+		}
+
+		for _, c := range comparisons {
+			// the controller truncates long comments
+			if c.Got[0] != Truncated(c.Want, typ) {
+				t.Errorf("Bad %s[%d]. Got %q, want %q", typ, c.Id, c.Got[0], Truncated(c.Want, typ))
+			}
+		}
+	}
+
+	// and comments can be set on it
+	target, err := NewTarget(dir, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []Type{Numreg, Posreg, Sreg, Ualm, Din, Aout} {
+		err = target.SetComment(typ, 1, "new")
+		if err != nil {
+			t.Errorf("SetComment(%s): %s", typ, err)
+		}
+	}
+}
+
+func TestGenerateValues(t *testing.T) {
+	src, err := generateVA(Numreg, []Definition{{Numreg, 1, "count", "12"}, {Numreg, 2, "time", "1.500000"}, {Numreg, 3, "none", ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"  [1] = 12  'count' \n", "  [2] = 1.500000  'time' \n", "  [3] = 0  'none' \n"} {
+		if !strings.Contains(src, line) {
+			t.Errorf("numreg.va does not contain %q", line)
+		}
+	}
+
+	src, err = generateVA(Sreg, []Definition{{Sreg, 1, "recipe", "A"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := "  [1] = 'A'  'recipe' \n"; !strings.Contains(src, line) {
+		t.Errorf("strreg.va does not contain %q", line)
+	}
+
+	_, err = generateVA(Sreg, []Definition{{Sreg, 1, "recipe", "it's"}})
+	if want := `value "it's" for SR[1] cannot contain a single quote`; err == nil || err.Error() != want {
+		t.Errorf("Bad error. Got %v, want %q", err, want)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	_, err := generateVA(Numreg, []Definition{{Numreg, 1, "it's", ""}})
+	if want := `comment "it's" for R[1] cannot contain a single quote`; err == nil || err.Error() != want {
+		t.Errorf("Bad error. Got %v, want %q", err, want)
+	}
+
+	_, err = generateIO(map[Type][]Definition{Din: {{Din, 0, "zero", ""}}})
+	if want := "DI[0] is out of range"; err == nil || err.Error() != want {
+		t.Errorf("Bad error. Got %v, want %q", err, want)
+	}
+}
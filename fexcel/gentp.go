@@ -0,0 +1,287 @@
+package fexcel
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the KAREL program called by generated TP programs to set comments
+const setcmtName = "SETCMT"
+
+// type arguments to SETCMT
+var setcmtCodes = map[Type]int{
+	Numreg: 1,
+	Posreg: 2,
+	Sreg:   3,
+	Ualm:   4,
+	Din:    5,
+	Dout:   6,
+	Rin:    7,
+	Rout:   8,
+	Gin:    9,
+	Gout:   10,
+	Ain:    11,
+	Aout:   12,
+	Flag:   13,
+}
+
+// SetcmtSource is the KAREL source of SETCMT. It must be compiled and
+// loaded before the generated programs are run.
+const SetcmtSource = `PROGRAM setcmt
+-- Sets a comment from a TP program, e.g. CALL SETCMT(1, 5, 'Part count')
+-- Types: 1 R, 2 PR, 3 SR, 4 UALM, 5 DI, 6 DO, 7 RI, 8 RO, 9 GI,
+--        10 GO, 11 AI, 12 AO, 13 F
+-- Generated by fexcel.
+%COMMENT = 'fexcel comments'
+%NOLOCKGROUP
+%NOBUSYLAMP
+%INCLUDE kliotyps
+
+VAR
+  typ, id, entry, status : INTEGER
+  cmt                    : STRING[29]
+  var_name               : STRING[20]
+
+ROUTINE fail(msg : STRING)
+BEGIN
+  WRITE TPERROR ('SETCMT: ', msg, CR)
+  ABORT
+END fail
+
+ROUTINE int_prm(n : INTEGER) : INTEGER
+VAR
+  data_type, int_value, status : INTEGER
+  real_value                   : REAL
+  str_value                    : STRING[1]
+BEGIN
+  GET_TPE_PRM(n, data_type, int_value, real_value, str_value, status)
+  IF (status <> 0) OR (data_type <> 1) THEN
+    fail('argument must be an integer')
+  ENDIF
+  RETURN(int_value)
+END int_prm
+
+ROUTINE str_prm(n : INTEGER) : STRING
+VAR
+  data_type, int_value, status : INTEGER
+  real_value                   : REAL
+  str_value                    : STRING[29]
+BEGIN
+  GET_TPE_PRM(n, data_type, int_value, real_value, str_value, status)
+  IF (status <> 0) OR (data_type <> 3) THEN
+    fail('argument must be a string')
+  ENDIF
+  RETURN(str_value)
+END str_prm
+
+BEGIN
+  typ = int_prm(1)
+  id = int_prm(2)
+  cmt = str_prm(3)
+
+  SELECT typ OF
+    CASE(1): SET_REG_CMT(id, cmt, status)
+    CASE(2): SET_PREG_CMT(id, cmt, status)
+    CASE(3): SET_SREG_CMT(id, cmt, status)
+    CASE(4):
+      CNV_INT_STR(id, 1, 0, var_name)
+      var_name = '$UALRM_MSG[' + SUB_STR(var_name, 2, STR_LEN(var_name) - 1) + ']'
+      SET_VAR(entry, '*SYSTEM*', var_name, cmt, status)
+    CASE(5): SET_PORT_CMT(io_din, id, cmt, status)
+    CASE(6): SET_PORT_CMT(io_dout, id, cmt, status)
+    CASE(7): SET_PORT_CMT(io_rdi, id, cmt, status)
+    CASE(8): SET_PORT_CMT(io_rdo, id, cmt, status)
+    CASE(9): SET_PORT_CMT(io_gpin, id, cmt, status)
+    CASE(10): SET_PORT_CMT(io_gpout, id, cmt, status)
+    CASE(11): SET_PORT_CMT(io_anin, id, cmt, status)
+    CASE(12): SET_PORT_CMT(io_anout, id, cmt, status)
+    CASE(13): SET_PORT_CMT(io_flag, id, cmt, status)
+    ELSE:
+      fail('unknown type')
+  ENDSELECT
+
+  IF status <> 0 THEN
+    POST_ERR(status, '', 0, 0)
+    fail('comment not set')
+  ENDIF
+END setcmt
+`
+
+// valid TP program names
+var tpNameRegexp = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// the longest TP program name
+const maxTPNameLength = 36
+
+// the longest TP program comment
+const maxTPCommentLength = 16
+
+type TPGenerator struct {
+	file *File
+
+	Definitions map[Type][]Definition
+	MaxLines    int // per program; 0 for no limit
+}
+
+// NewTPGenerator writes the definitions of the spreadsheet at path to
+// TP programs that set the comments on a controller
+func NewTPGenerator(path string, cfg Config, maxLines int) (*TPGenerator, error) {
+	if maxLines < 0 {
+		return nil, fmt.Errorf("max lines must not be negative")
+	}
+
+	f, err := OpenFile(path, cfg.FileConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	defs, err := f.AllDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TPGenerator{file: f, Definitions: defs, MaxLines: maxLines}, nil
+}
+
+func (g *TPGenerator) Warnings() []string {
+	return g.file.Warnings
+}
+
+// lines returns a SETCMT call for every definition in type order
+func (g *TPGenerator) lines() ([]string, error) {
+	var types []Type
+	for t := range g.Definitions {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	var lines []string
+	for _, t := range types {
+		code, ok := setcmtCodes[t]
+		if !ok {
+			return nil, fmt.Errorf("cannot set %s comments from a TP program", t)
+		}
+
+		for _, d := range g.Definitions[t] {
+			comment := Truncated(d.Comment, t)
+			if strings.Contains(comment, "'") {
+				return nil, fmt.Errorf("comment %q for %s[%d] cannot contain a single quote", comment, t, d.Id)
+			}
+
+			lines = append(lines, fmt.Sprintf("CALL %s(%d,%d,'%s')", setcmtName, code, d.Id, comment))
+		}
+	}
+
+	return lines, nil
+}
+
+// tpProgram returns the contents of an .ls file. The comment is cut
+// to maxTPCommentLength, or the controller won't load the program.
+func tpProgram(name string, comment string, lines []string) string {
+	if len(comment) > maxTPCommentLength {
+		comment = comment[:maxTPCommentLength]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "/PROG  %s\n", name)
+	b.WriteString("/ATTR\n")
+	fmt.Fprintf(&b, "COMMENT\t\t= %q;\n", comment)
+	b.WriteString("DEFAULT_GROUP\t= *,*,*,*,*;\n")
+	b.WriteString("/MN\n")
+	for _, l := range lines {
+		fmt.Fprintf(&b, " : %s ;\n", l)
+	}
+	b.WriteString("/POS\n")
+	b.WriteString("/END\n")
+	return b.String()
+}
+
+// Programs returns the programs to write by name. When the calls don't
+// fit in a single program, they are split into programs named after
+// the first one (e.g. SETCMTS1, SETCMTS2), and the first program calls
+// each of them in turn.
+func (g *TPGenerator) Programs(name string) (names []string, programs map[string]string, err error) {
+	if !tpNameRegexp.MatchString(name) {
+		return nil, nil, fmt.Errorf("%q is not a valid TP program name", name)
+	}
+	if name == setcmtName {
+		return nil, nil, fmt.Errorf("%s is the name of the KAREL program that sets the comments", name)
+	}
+
+	lines, err := g.lines()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	programs = make(map[string]string)
+	if g.MaxLines == 0 || len(lines) <= g.MaxLines {
+		if len(name) > maxTPNameLength {
+			return nil, nil, fmt.Errorf("program name %s is longer than %d characters", name, maxTPNameLength)
+		}
+
+		programs[name] = tpProgram(name, "fexcel comments", lines)
+		return []string{name}, programs, nil
+	}
+
+	var calls []string
+	for i := 0; i*g.MaxLines < len(lines); i++ {
+		part := name + strconv.Itoa(i+1)
+		if len(part) > maxTPNameLength {
+			return nil, nil, fmt.Errorf("program name %s is longer than %d characters", part, maxTPNameLength)
+		}
+
+		end := (i + 1) * g.MaxLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		names = append(names, part)
+		programs[part] = tpProgram(part, "fexcel cmts "+strconv.Itoa(i+1), lines[i*g.MaxLines:end])
+		calls = append(calls, "CALL "+part)
+	}
+
+	if len(calls) > g.MaxLines {
+		return nil, nil, fmt.Errorf("%d programs are too many for %s to call in %d lines", len(calls), name, g.MaxLines)
+	}
+
+	programs[name] = tpProgram(name, "fexcel comments", calls)
+	return append([]string{name}, names...), programs, nil
+}
+
+// Generate writes the programs to path (and next to it if they are
+// split) along with the source of the KAREL program they call.
+func (g *TPGenerator) Generate(w io.Writer, path string) error {
+	dir, base := filepath.Split(path)
+	ext := filepath.Ext(base)
+	if !strings.EqualFold(ext, ".ls") {
+		return fmt.Errorf("output path %q must end in .ls", path)
+	}
+
+	names, programs, err := g.Programs(strings.ToUpper(strings.TrimSuffix(base, ext)))
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		fpath := filepath.Join(dir, name+ext)
+		if i == 0 {
+			fpath = path
+		}
+
+		fmt.Fprintf(w, "Writing %s\n", fpath)
+		err = ioutil.WriteFile(fpath, []byte(programs[name]), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	fpath := filepath.Join(dir, strings.ToLower(setcmtName)+".kl")
+	fmt.Fprintf(w, "Writing %s\n", fpath)
+	return ioutil.WriteFile(fpath, []byte(SetcmtSource), 0644)
+}
@@ -0,0 +1,120 @@
+package fexcel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTPPrograms(t *testing.T) {
+	g := TPGenerator{
+		Definitions: map[Type][]Definition{
+			Din:    {{Din, 1, "Part present", ""}, {Din, 2, "", ""}},
+			Numreg: {{Numreg, 1, "this is an extremely long comment", ""}},
+			Ualm:   {{Ualm, 3, "Gripper failed to close", ""}},
+		},
+	}
+
+	names, programs, err := g.Programs("SETCMTS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "SETCMTS" {
+		t.Fatalf("Bad names: %v", names)
+	}
+
+	want := `/PROG  SETCMTS
+/ATTR
+COMMENT		= "fexcel comments";
+DEFAULT_GROUP	= *,*,*,*,*;
+/MN
+ : CALL SETCMT(1,1,'this is an extre') ;
+ : CALL SETCMT(4,3,'Gripper failed to close') ;
+ : CALL SETCMT(5,1,'Part present') ;
+ : CALL SETCMT(5,2,'') ;
+/POS
+/END
+`
+	if programs["SETCMTS"] != want {
+		t.Errorf("Bad program. Got\n%s\nwant\n%s", programs["SETCMTS"], want)
+	}
+
+	// split programs are called by the first one
+	g.MaxLines = 3
+	names, programs, err = g.Programs("SETCMTS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "SETCMTS,SETCMTS1,SETCMTS2" {
+		t.Fatalf("Bad names: %v", names)
+	}
+	if !strings.Contains(programs["SETCMTS"], " : CALL SETCMTS1 ;\n : CALL SETCMTS2 ;\n") {
+		t.Errorf("Bad main program:\n%s", programs["SETCMTS"])
+	}
+	if n := strings.Count(programs["SETCMTS1"], "CALL SETCMT("); n != 3 {
+		t.Errorf("Bad SETCMTS1 line count. Got %d, want 3", n)
+	}
+	if n := strings.Count(programs["SETCMTS2"], "CALL SETCMT("); n != 1 {
+		t.Errorf("Bad SETCMTS2 line count. Got %d, want 1", n)
+	}
+
+	// the controller won't load programs with longer comments
+	for _, name := range names {
+		m := regexp.MustCompile(`COMMENT\t\t= "(.*)";`).FindStringSubmatch(programs[name])
+		if m == nil || len(m[1]) > maxTPCommentLength {
+			t.Errorf("Bad %s comment: %q", name, m)
+		}
+	}
+	if !strings.Contains(programs["SETCMTS2"], `COMMENT		= "fexcel cmts 2";`) {
+		t.Errorf("Bad SETCMTS2 comment:\n%s", programs["SETCMTS2"])
+	}
+}
+
+func TestTPProgramErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		defs map[Type][]Definition
+		want string
+	}{
+		{"setcmts", nil, `"setcmts" is not a valid TP program name`},
+		{"SETCMT", nil, "SETCMT is the name of the KAREL program that sets the comments"},
+		{"SETCMTS", map[Type][]Definition{Numreg: {{Numreg, 1, "it's", ""}}}, `comment "it's" for R[1] cannot contain a single quote`},
+	}
+
+	for _, test := range tests {
+		g := TPGenerator{Definitions: test.defs}
+		_, _, err := g.Programs(test.name)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Bad error. Got %v, want %q", err, test.want)
+		}
+	}
+}
+
+func TestTPGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fexcel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := Config{FileConfig: FileConfig{Sheet: "IO", Offset: 1, Dins: []string{"A2"}, Douts: []string{"C2"}}}
+	g, err := NewTPGenerator("testdata/test.xlsx", cfg, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = g.Generate(ioutil.Discard, filepath.Join(dir, "setcmts.ls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3 DIs and 4 DOs
+	for _, name := range []string{"setcmts.ls", "SETCMTS1.ls", "SETCMTS2.ls", "setcmt.kl"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
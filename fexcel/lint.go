@@ -0,0 +1,206 @@
+package fexcel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+	"github.com/olekukonko/tablewriter"
+)
+
+type Severity int
+
+const (
+	LintWarning Severity = iota
+	LintError
+)
+
+func (s Severity) String() string {
+	if s == LintError {
+		return "error"
+	}
+	return "warning"
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// An Issue is a problem found by lint
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Cell     string   `json:"cell"` // e.g. [Data]B4
+	Type     Type     `json:"type"`
+	Id       int      `json:"id"`
+	Msg      string   `json:"message"`
+}
+
+type LintCommand struct {
+	file   *File
+	target *Target // optional, for checking that ids exist
+
+	Rules *Rules // optional naming conventions
+}
+
+// NewLintCommand checks the spreadsheet at path. If a target is
+// provided, ids that don't exist on it are reported too.
+func NewLintCommand(path string, cfg Config, targetPath string) (*LintCommand, error) {
+	f, err := OpenFile(path, cfg.FileConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	l := LintCommand{file: f}
+
+	if targetPath != "" {
+		l.target, err = NewTarget(targetPath, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &l, nil
+}
+
+// commentCell returns the name of the comment cell next to c
+func (f *File) commentCell(c cell) string {
+	col, _, _ := excelize.CellNameToCoordinates(c.loc.Axis)
+	axis, _ := excelize.CoordinatesToCellName(col+f.offsetFor(c.loc), c.row)
+	return fmt.Sprintf("[%s]%s", c.loc.Sheet, axis)
+}
+
+// undisplayable returns the characters of the comment that the pendant
+// can't display
+func undisplayable(comment string) (bad []string) {
+	for _, r := range comment {
+		if r < ' ' || r > '~' {
+			bad = append(bad, strconv.QuoteRune(r))
+		}
+	}
+	return
+}
+
+// Lint checks the definitions of every type and returns the issues
+// found in type order. Cells that can't be read are reported as
+// errors, and the rest of the type is still checked.
+func (l *LintCommand) Lint() ([]Issue, error) {
+	var types []Type
+	for t := range l.file.Locations {
+		if t != Constant {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	issues := []Issue{}
+	for _, t := range types {
+		// the rows that can be read are still checked, and duplicates
+		// across locations are reported below
+		defs, cells, err := l.file.readableDefinitions(t)
+		if errs, ok := err.(ReadErrorList); ok {
+			for _, e := range errs {
+				issues = append(issues, Issue{LintError, fmt.Sprintf("[%s]%s", e.Sheet, e.Axis), t, 0, e.Msg})
+			}
+		} else if err != nil {
+			return nil, err
+		}
+
+		if l.target != nil {
+			err = l.target.GetComments(t)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		ids := make(map[int]int)
+		comments := make(map[string]int)
+		for i, d := range defs {
+			idCell, commentCell := cells[i].String(), l.file.commentCell(cells[i])
+			issue := func(s Severity, cell string, format string, args ...interface{}) {
+				issues = append(issues, Issue{s, cell, t, d.Id, fmt.Sprintf(format, args...)})
+			}
+
+			if j, seen := ids[d.Id]; seen {
+				issue(LintError, idCell, "%s[%d] is already defined in %s", t, d.Id, cells[j])
+			} else {
+				ids[d.Id] = i
+			}
+
+			if d.Id < 1 || d.Id > MaxId(t) {
+				issue(LintError, idCell, "%s[%d] is out of range (1-%d)", t, d.Id, MaxId(t))
+			} else if l.target != nil {
+				if _, ok := l.target.Comments[t][d.Id]; !ok {
+					issue(LintError, idCell, "%s[%d] does not exist on %s", t, d.Id, l.target.Name)
+				}
+			}
+
+			if d.Comment == "" {
+				continue
+			}
+
+			if j, seen := comments[d.Comment]; seen {
+				issue(LintError, commentCell, "comment %q for %s[%d] is also used by %s[%d]", d.Comment, t, d.Id, t, defs[j].Id)
+			} else {
+				comments[d.Comment] = i
+			}
+
+			if maxLength := MaxLengthFor(t); len(d.Comment) > maxLength {
+				issue(LintWarning, commentCell, "comment %q for %s[%d] is longer than %d characters", d.Comment, t, d.Id, maxLength)
+			}
+
+			if strings.TrimSpace(d.Comment) != d.Comment {
+				issue(LintWarning, commentCell, "comment %q for %s[%d] has leading or trailing whitespace", d.Comment, t, d.Id)
+			}
+
+			if bad := undisplayable(d.Comment); len(bad) > 0 {
+				issue(LintError, commentCell, "comment %q for %s[%d] has characters the pendant can't display: %s", d.Comment, t, d.Id, strings.Join(bad, " "))
+			}
+
+			if l.Rules != nil {
+				for _, msg := range l.Rules.Check(t, cells[i].loc.Sheet, d.Comment) {
+					issue(LintError, commentCell, "comment %q for %s[%d] %s", d.Comment, t, d.Id, msg)
+				}
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+// CountIssues returns the number of issues with the provided severity
+func CountIssues(issues []Issue, s Severity) (i int) {
+	for _, issue := range issues {
+		if issue.Severity == s {
+			i++
+		}
+	}
+	return i
+}
+
+func (l *LintCommand) FprintTable(w io.Writer, issues []Issue) {
+	if len(issues) > 0 {
+		table := tablewriter.NewWriter(w)
+		table.SetAutoWrapText(false)
+		table.SetAutoFormatHeaders(false)
+		table.SetHeader([]string{"Severity", "Cell", "Type", "Id", "Message"})
+
+		for _, i := range issues {
+			table.Append([]string{i.Severity.String(), i.Cell, i.Type.String(), strconv.Itoa(i.Id), i.Msg})
+		}
+
+		table.Render()
+	}
+
+	errors, warnings := CountIssues(issues, LintError), CountIssues(issues, LintWarning)
+	fmt.Fprintf(w, "%d %s, %d %s\n", errors, Pluralize("error", errors), warnings, Pluralize("warning", warnings))
+}
+
+func (l *LintCommand) FprintJSON(w io.Writer, issues []Issue) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issues)
+}
@@ -0,0 +1,135 @@
+package fexcel
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir, fpath := tempSpreadsheet(t)
+	defer os.RemoveAll(dir)
+
+	cfg := Config{FileConfig: FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2"}, Dins: []string{"IO:A2"}}}
+
+	f, err := OpenFile(fpath, cfg.FileConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ids 1-5 are in A2:A6
+	rows := [][]interface{}{
+		{3, "three"},
+		{6, " six"},
+		{7, "temp °C"},
+		{0, "zero"},
+		{500, "big"},
+		{5000, "huge"},
+	}
+	for i, row := range rows {
+		f.SetValue("Data", 1, i+7, row[0])
+		f.SetValue("Data", 2, i+7, row[1])
+	}
+	f.SetValue("IO", 1, 3, "DI2")
+
+	err = f.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewLintCommand(fpath, cfg, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := l.Lint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Issue{
+		{LintWarning, "[Data]B2", Numreg, 1, `comment "this is an extremely long comment" for R[1] is longer than 16 characters`},
+		{LintError, "[Data]A7", Numreg, 3, "R[3] is already defined in [Data]A4"},
+		{LintError, "[Data]B7", Numreg, 3, `comment "three" for R[3] is also used by R[3]`},
+		{LintWarning, "[Data]B8", Numreg, 6, `comment " six" for R[6] has leading or trailing whitespace`},
+		{LintError, "[Data]B9", Numreg, 7, `comment "temp °C" for R[7] has characters the pendant can't display: '°'`},
+		{LintError, "[Data]A10", Numreg, 0, "R[0] is out of range (1-3000)"},
+		{LintError, "[Data]A11", Numreg, 500, "R[500] does not exist on testdata"},
+		{LintError, "[Data]A12", Numreg, 5000, "R[5000] is out of range (1-3000)"},
+		{LintError, "[IO]A3", Din, 0, `DI id "DI2" is not a number`},
+		{LintError, "[IO]A2", Din, 1, "DI[1] does not exist on testdata"},
+		{LintError, "[IO]A4", Din, 3, "DI[3] does not exist on testdata"},
+	}
+
+	if len(issues) != len(expected) {
+		t.Fatalf("Bad length. Got %d, want %d: %v", len(issues), len(expected), issues)
+	}
+	for i, issue := range issues {
+		if issue != expected[i] {
+			t.Errorf("Bad issue.\nGot  %v\nwant %v", issue, expected[i])
+		}
+	}
+
+	if got := CountIssues(issues, LintError); got != 9 {
+		t.Errorf("Bad error count. Got %d, want %d", got, 9)
+	}
+
+	var b bytes.Buffer
+	l.FprintTable(&b, issues)
+	if !strings.HasSuffix(b.String(), "9 errors, 2 warnings\n") {
+		t.Errorf("Bad summary:\n%s", b.String())
+	}
+}
+
+func TestLintUnreadableRows(t *testing.T) {
+	dir, fpath := tempSpreadsheet(t)
+	defer os.RemoveAll(dir)
+
+	cfg := Config{FileConfig: FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2", "More:B3"}}}
+
+	f, err := OpenFile(fpath, cfg.FileConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.CreateSheet("More")
+	f.SetValue("More", 2, 3, "R10")
+	f.SetValue("More", 3, 3, "ten")
+	f.SetValue("More", 2, 4, 3)
+	f.SetValue("More", 3, 4, "three again")
+	f.SetValue("More", 2, 5, 5000)
+	f.SetValue("More", 3, 5, "huge")
+
+	err = f.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewLintCommand(fpath, cfg, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := l.Lint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the unreadable id doesn't hide the other rows
+	expected := []Issue{
+		{LintError, "[More]B3", Numreg, 0, `R id "R10" is not a number`},
+		{LintWarning, "[Data]B2", Numreg, 1, `comment "this is an extremely long comment" for R[1] is longer than 16 characters`},
+		{LintError, "[More]B4", Numreg, 3, "R[3] is already defined in [Data]A4"},
+		{LintError, "[More]B5", Numreg, 5000, "R[5000] is out of range (1-3000)"},
+	}
+
+	if len(issues) != len(expected) {
+		t.Fatalf("Bad length. Got %d, want %d: %v", len(issues), len(expected), issues)
+	}
+	for i, issue := range issues {
+		if issue != expected[i] {
+			t.Errorf("Bad issue.\nGot  %v\nwant %v", issue, expected[i])
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package fexcel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+// resolve sets the sheet, axis and offset of a location given by a
+// defined name, a header or a table, and decides where it ends.
+func (f *File) resolve(loc *Location) error {
+	var err error
+	if !loc.Resolved() {
+		switch {
+		case loc.Name != "":
+			err = f.resolveName(loc)
+		case loc.Table != "":
+			err = f.resolveTable(loc)
+		case loc.Header != "":
+			err = f.resolveHeader(loc)
+		default:
+			err = fmt.Errorf("location %s is empty", loc)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// tables and ranges end at a known row
+	if loc.End != 0 {
+		return nil
+	}
+
+	loc.Blanks, err = f.Config.blanks()
+	return err
+}
+
+// resolveName starts the location at the top-left cell of the defined
+// name's range. When the range spans more than one column, the comments
+// are expected in the last column. Ranges of more than one row are read
+// to their last row.
+func (f *File) resolveName(loc *Location) error {
+	xlsx, ok := f.excel()
+	if !ok {
+		return fmt.Errorf("defined name %q: defined names are only supported in .xlsx files", loc.Name)
+	}
+
+	var refersTo string
+	for _, dn := range xlsx.GetDefinedName() {
+		if strings.EqualFold(dn.Name, loc.Name) {
+			refersTo = dn.RefersTo
+			break
+		}
+	}
+	if refersTo == "" {
+		return fmt.Errorf("defined name %q not found", loc.Name)
+	}
+
+	// e.g. IO!$A$2:$B$50 or 'My Sheet'!$A$2
+	refersTo = strings.TrimPrefix(refersTo, "=")
+	i := strings.LastIndex(refersTo, "!")
+	if i < 0 || strings.Contains(refersTo, ",") {
+		return fmt.Errorf("defined name %q does not refer to a single range: %q", loc.Name, refersTo)
+	}
+
+	sheet := refersTo[:i]
+	if strings.HasPrefix(sheet, "'") && strings.HasSuffix(sheet, "'") {
+		sheet = strings.Replace(sheet[1:len(sheet)-1], "''", "'", -1)
+	}
+
+	cells := strings.Split(strings.Replace(refersTo[i+1:], "$", "", -1), ":")
+	first, firstRow, err := excelize.CellNameToCoordinates(cells[0])
+	if err != nil {
+		return fmt.Errorf("defined name %q does not refer to a cell range: %q", loc.Name, refersTo)
+	}
+
+	offset, end := 0, 0
+	if len(cells) == 2 {
+		last, lastRow, err := excelize.CellNameToCoordinates(cells[1])
+		if err != nil {
+			return fmt.Errorf("defined name %q does not refer to a cell range: %q", loc.Name, refersTo)
+		}
+		offset = last - first
+
+		if lastRow > firstRow {
+			end = lastRow
+		}
+	}
+
+	loc.Sheet, loc.Axis, loc.End = sheet, cells[0], end
+	if offset != 0 {
+		loc.Offset = offset
+	}
+
+	return nil
+}
+
+// resolveHeader starts the location below the first cell labeled with
+// the header. Unless an offset was provided, the comments are in the
+// nearest column to the right labeled with the comment header.
+func (f *File) resolveHeader(loc *Location) error {
+	rows, err := f.book.GetRows(loc.Sheet)
+	if err != nil {
+		return err
+	}
+
+	for r, row := range rows {
+		for c, value := range row {
+			if !headerEqual(value, loc.Header) {
+				continue
+			}
+
+			axis, err := excelize.CoordinatesToCellName(c+1, r+2)
+			if err != nil {
+				return err
+			}
+
+			if loc.Offset == 0 {
+				for cc := c + 1; cc < len(row); cc++ {
+					if headerEqual(row[cc], loc.CommentHeader) {
+						loc.Offset = cc - c
+						break
+					}
+				}
+				if loc.Offset == 0 {
+					return fmt.Errorf("header %q not found to the right of %q on sheet %q", loc.CommentHeader, loc.Header, loc.Sheet)
+				}
+			}
+
+			loc.Axis = axis
+			return nil
+		}
+	}
+
+	return fmt.Errorf("header %q not found on sheet %q", loc.Header, loc.Sheet)
+}
+
+func headerEqual(value, header string) bool {
+	return strings.EqualFold(strings.TrimSpace(value), header)
+}
@@ -0,0 +1,355 @@
+package fexcel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// A Manifest is a text alternative to a spreadsheet. It holds the ids,
+// comments and values (see FormatValue) of each type along with the
+// constants, and can be stored as JSON or YAML.
+type Manifest struct {
+	Types     map[Type][]ManifestEntry `json:"types" yaml:"types"`
+	Constants map[string]string        `json:"constants,omitempty" yaml:"constants,omitempty"`
+}
+
+type ManifestEntry struct {
+	Id      int    `json:"id" yaml:"id"`
+	Comment string `json:"comment" yaml:"comment"`
+	Value   string `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// isManifest reports whether path is a JSON or YAML manifest
+func isManifest(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+func ReadManifest(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &m)
+	} else {
+		err = yaml.UnmarshalStrict(b, &m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	if _, ok := m.Types[Constant]; ok {
+		return nil, fmt.Errorf("%s: constants belong in constants, not types", path)
+	}
+
+	return &m, nil
+}
+
+// Save writes the manifest to path as JSON or YAML depending on its
+// extension. Entries are sorted by id.
+func (m *Manifest) Save(path string) error {
+	if !isManifest(path) {
+		return fmt.Errorf("manifest path %q must end in .json, .yaml or .yml", path)
+	}
+
+	for _, entries := range m.Types {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Id < entries[j].Id })
+	}
+
+	var b []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		b, err = json.MarshalIndent(m, "", "  ")
+		b = append(b, '\n')
+	} else {
+		b, err = yaml.Marshal(m)
+	}
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// Manifest returns the definitions and constants of the file
+func (f *File) Manifest() (*Manifest, error) {
+	defs, err := f.AllDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	m := Manifest{Types: make(map[Type][]ManifestEntry)}
+	for t, d := range defs {
+		entries := []ManifestEntry{}
+		for _, def := range d {
+			entries = append(entries, ManifestEntry{Id: def.Id, Comment: def.Comment, Value: def.Value})
+		}
+		m.Types[t] = entries
+	}
+
+	if _, defined := f.Locations[Constant]; defined {
+		m.Constants, err = f.Constants()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &m, nil
+}
+
+// openManifest opens a manifest as a File. Each type is read from a
+// sheet named after it (e.g. R), with ids in column A, comments in
+// column B and values in column C (or PR positions in columns C to K),
+// so the location specs and offsets of cfg are not used.
+func openManifest(path string, cfg FileConfig) (*File, error) {
+	m, err := ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := manifestWorkbook{sheets: make(map[string][][]string)}
+	f := File{path: path, book: &w, Config: cfg, Locations: make(map[Type][]*Location)}
+	f.Config.ValueOffset = 2
+
+	add := func(t Type) {
+		w.sheets[t.String()] = [][]string{}
+		f.Locations[t] = []*Location{{Sheet: t.String(), Axis: "A1", Offset: 1, Blanks: 1}}
+	}
+
+	for t, entries := range m.Types {
+		add(t)
+		for _, e := range entries {
+			row := []string{strconv.Itoa(e.Id), e.Comment, e.Value}
+
+			// positions are spread across their columns like a spreadsheet
+			if t == Posreg {
+				p, err := ParsePosition(e.Value)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s[%d]: %s", path, t, e.Id, err)
+				}
+				row = row[:2]
+				if p != nil {
+					row = append(row, p.cells()...)
+				}
+			}
+
+			w.sheets[t.String()] = append(w.sheets[t.String()], row)
+		}
+	}
+
+	if len(m.Constants) > 0 {
+		add(Constant)
+
+		var names []string
+		for name := range m.Constants {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			w.sheets[Constant.String()] = append(w.sheets[Constant.String()], []string{name, m.Constants[name]})
+		}
+	}
+
+	return &f, nil
+}
+
+// A manifestWorkbook holds a manifest in memory as one sheet per type
+type manifestWorkbook struct {
+	sheets map[string][][]string
+}
+
+func (w *manifestWorkbook) sheet(name string) ([][]string, error) {
+	records, ok := w.sheets[name]
+	if !ok {
+		return nil, fmt.Errorf("sheet %s is not exist", name)
+	}
+	return records, nil
+}
+
+func (w *manifestWorkbook) GetCellValue(sheet, axis string) (string, error) {
+	records, err := w.sheet(sheet)
+	if err != nil {
+		return "", err
+	}
+
+	return cellValue(records, axis)
+}
+
+func (w *manifestWorkbook) SetCellValue(sheet, axis string, value interface{}) error {
+	records, err := w.sheet(sheet)
+	if err != nil {
+		return err
+	}
+
+	w.sheets[sheet], err = setCell(records, axis, value)
+	return err
+}
+
+func (w *manifestWorkbook) GetRows(sheet string) ([][]string, error) {
+	return w.sheet(sheet)
+}
+
+func (w *manifestWorkbook) NewSheet(name string) int {
+	if _, ok := w.sheets[name]; !ok {
+		w.sheets[name] = [][]string{}
+	}
+	return len(w.sheets)
+}
+
+// SaveAs writes the sheets back out as a manifest
+func (w *manifestWorkbook) SaveAs(path string) error {
+	m := Manifest{Types: make(map[Type][]ManifestEntry)}
+
+	for name, records := range w.sheets {
+		t, err := ParseType(name)
+		if err != nil {
+			return fmt.Errorf("sheet %q is not a type", name)
+		}
+
+		if t == Constant {
+			m.Constants = make(map[string]string)
+			for _, r := range records {
+				if len(r) > 0 && r[0] != "" {
+					m.Constants[r[0]], _ = cellValue([][]string{r}, "B1")
+				}
+			}
+			continue
+		}
+
+		entries := []ManifestEntry{}
+		for _, r := range records {
+			if len(r) == 0 || r[0] == "" {
+				continue
+			}
+
+			id, err := strconv.Atoi(r[0])
+			if err != nil {
+				return fmt.Errorf("%s id %q is not a number", t, r[0])
+			}
+
+			comment, _ := cellValue([][]string{r}, "B1")
+			value, _ := cellValue([][]string{r}, "C1")
+			if t == Posreg {
+				cells := make([]string, positionColumns)
+				if len(r) > 2 {
+					copy(cells, r[2:])
+				}
+
+				p, err := positionFromCells(cells)
+				if err != nil {
+					return fmt.Errorf("%s[%d]: %s", t, id, err)
+				}
+				value = ""
+				if p != nil {
+					value = p.String()
+				}
+			}
+			entries = append(entries, ManifestEntry{Id: id, Comment: comment, Value: value})
+		}
+		m.Types[t] = entries
+	}
+
+	return m.Save(path)
+}
+
+type Importer struct {
+	manifest *Manifest
+	file     *File
+	headers  bool
+}
+
+// NewImporter writes the manifest at manifestPath to a new spreadsheet
+// at path, laid out by cfg.
+func NewImporter(manifestPath string, path string, cfg FileConfig, headers bool) (*Importer, error) {
+	if isManifest(path) {
+		return nil, fmt.Errorf("cannot import %q into another manifest", manifestPath)
+	}
+
+	m, err := ReadManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hasOverlaps, err := cfg.HasOverlaps()
+	if err != nil {
+		return nil, err
+	}
+	if hasOverlaps {
+		return nil, fmt.Errorf("configuration has overlapping columns")
+	}
+
+	f, err := NewFile(path, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// everything in the manifest must have somewhere to go
+	for t := range m.Types {
+		if _, defined := f.Locations[t]; !defined {
+			return nil, fmt.Errorf("manifest has %ss, but no location is defined for them", t)
+		}
+	}
+	if _, defined := f.Locations[Constant]; len(m.Constants) > 0 && !defined {
+		return nil, fmt.Errorf("manifest has constants, but no location is defined for them")
+	}
+
+	return &Importer{manifest: m, file: f, headers: headers}, nil
+}
+
+func (i *Importer) Import(w io.Writer) error {
+	fmt.Fprintf(w, "Creating file: %s\n", i.file.path)
+
+	var types []Type
+	for t := range i.file.Locations {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(a, b int) bool { return types[a] < types[b] })
+
+	for _, t := range types {
+		var ids []interface{}
+		var comments, values []string
+
+		if t == Constant {
+			var names []string
+			for name := range i.manifest.Constants {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				ids = append(ids, name)
+				comments = append(comments, i.manifest.Constants[name])
+			}
+		} else {
+			for _, e := range i.manifest.Types[t] {
+				ids = append(ids, e.Id)
+				comments = append(comments, e.Comment)
+				values = append(values, e.Value)
+			}
+		}
+
+		fmt.Fprintf(w, "Writing %d %ss\n", len(ids), t)
+		err := i.file.writeList(t, i.headers, ids, comments, values)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w, "Saving file.")
+	return i.file.Save()
+}
@@ -0,0 +1,257 @@
+package fexcel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fexcel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2"}, Posregs: []string{"D2"}, Constants: []string{"M2"}}
+	f, err := OpenFile("testdata/test.xlsx", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := f.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"robot.yaml", "robot.json"} {
+		mpath := filepath.Join(dir, name)
+		err = m.Save(mpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the manifest is read the same way as the spreadsheet
+		manifest, err := OpenFile(mpath, FileConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, typ := range []Type{Numreg, Posreg} {
+			want, err := f.Definitions(typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := manifest.Definitions(typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("%s: Bad %s length. Got %d, want %d", name, typ, len(got), len(want))
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Errorf("%s: Bad definition. Got %v, want %v", name, got[i], want[i])
+				}
+			}
+		}
+
+		want, err := f.Constants()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := manifest.Constants()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: Bad constants. Got %v, want %v", name, got, want)
+		}
+		for k, v := range want {
+			if got[k] != v {
+				t.Errorf("%s: Bad constant %s. Got %q, want %q", name, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestManifestFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fexcel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := Manifest{
+		Types: map[Type][]ManifestEntry{
+			Din:    {{102, "two", ""}, {101, "one", ""}},
+			Numreg: {{1, "count", ""}},
+		},
+		Constants: map[string]string{"SPEED": "100"},
+	}
+
+	mpath := filepath.Join(dir, "robot.yml")
+	err = m.Save(mpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(mpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `types:
+  R:
+  - id: 1
+    comment: count
+  DI:
+  - id: 101
+    comment: one
+  - id: 102
+    comment: two
+constants:
+  SPEED: "100"
+`
+	if string(b) != want {
+		t.Errorf("Bad manifest. Got\n%s\nwant\n%s", b, want)
+	}
+
+	err = ioutil.WriteFile(mpath, []byte("types:\n  XX:\n  - id: 1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadManifest(mpath)
+	if err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+}
+
+func TestManifestDiffAndSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fexcel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mpath := filepath.Join(dir, "robot.json")
+	m := Manifest{Types: map[Type][]ManifestEntry{Numreg: {{2, "two", ""}, {3, "trois", ""}}}}
+	err = m.Save(mpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no cell locations are needed
+	d, err := NewDiffCommand(mpath, Config{}, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := d.Compare(Numreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 || !result[0].Equal() || result[1].Equal() {
+		t.Errorf("Bad comparisons: %v", result)
+	}
+
+	// pull and sync write through the same cells
+	f, err := OpenFile(mpath, FileConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, cells, err := f.definitions(Numreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.setComment(cells[1], "three")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.SetValue(Numreg.String(), 1, len(defs)+1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.SetValue(Numreg.String(), 2, len(defs)+1, "four")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := ReadManifest(mpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ManifestEntry{{2, "two", ""}, {3, "three", ""}, {4, "four", ""}}
+	got := saved.Types[Numreg]
+	if len(got) != len(want) {
+		t.Fatalf("Bad entries. Got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("Bad entry. Got %v, want %v", got[i], want[i])
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fexcel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mpath := filepath.Join(dir, "robot.yaml")
+	m := Manifest{
+		Types:     map[Type][]ManifestEntry{Din: {{1, "one", ""}, {2, "two", ""}}},
+		Constants: map[string]string{"SPEED": "100"},
+	}
+	err = m.Save(mpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fpath := filepath.Join(dir, "robot.xlsx")
+	cfg := FileConfig{Sheet: "IO", Offset: 1, Dins: []string{"A2"}}
+
+	_, err = NewImporter(mpath, fpath, cfg, true)
+	if want := "manifest has constants, but no location is defined for them"; err == nil || err.Error() != want {
+		t.Errorf("Bad error. Got %v, want %q", err, want)
+	}
+
+	cfg.Constants = []string{"Consts:A2"}
+	i, err := NewImporter(mpath, fpath, cfg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i.Import(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenFile(fpath, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Types[Din]) != 2 || got.Types[Din][1] != (ManifestEntry{2, "two", ""}) || got.Constants["SPEED"] != "100" {
+		t.Errorf("Bad import: %v", got)
+	}
+
+	header, err := f.readString("IO", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header != "DIs" {
+		t.Errorf("Bad header. Got %q, want %q", header, "DIs")
+	}
+}
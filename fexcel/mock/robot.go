@@ -0,0 +1,190 @@
+// Package mock provides a fake FANUC controller for exercising fexcel
+// without a real robot.
+//
+// A Robot is seeded from a backup directory and serves the MD: files
+// and KAREL comment tool endpoints that fexcel (via go-fanuc) uses.
+// Comment and value changes are kept in memory and optionally written
+// back to the backup directory.
+package mock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/onerobotics/fexcel/fexcel"
+)
+
+// comment tool function codes
+var commentTypes = map[int]fexcel.Type{
+	1:  fexcel.Numreg,
+	3:  fexcel.Posreg,
+	4:  fexcel.Ualm,
+	6:  fexcel.Rin,
+	7:  fexcel.Rout,
+	8:  fexcel.Din,
+	9:  fexcel.Dout,
+	10: fexcel.Gin,
+	11: fexcel.Gout,
+	12: fexcel.Ain,
+	13: fexcel.Aout,
+	14: fexcel.Sreg,
+	19: fexcel.Flag,
+}
+
+// comment tool function codes that set values
+var valueTypes = map[int]fexcel.Type{
+	2:  fexcel.Numreg,
+	15: fexcel.Sreg,
+}
+
+type Robot struct {
+	dir     string
+	persist bool
+
+	mux   sync.Mutex
+	files map[string]string // by lowercase filename
+}
+
+// NewRobot returns a Robot seeded with the files in dir. If persist is
+// true, comment changes are also written to dir.
+func NewRobot(dir string, persist bool) (*Robot, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := Robot{dir: dir, persist: persist, files: make(map[string]string)}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		r.files[strings.ToLower(info.Name())] = string(b)
+	}
+
+	return &r, nil
+}
+
+// File returns the current contents of a file on the MD: device.
+func (r *Robot) File(filename string) (string, bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	src, ok := r.files[strings.ToLower(filename)]
+	return src, ok
+}
+
+// SetComment sets the comment for t[id] as if it were set from the
+// teach pendant.
+func (r *Robot) SetComment(t fexcel.Type, id int, comment string) error {
+	return r.edit(t, func(src string) (string, error) {
+		return fexcel.ReplaceComment(t, src, id, fexcel.Truncated(comment, t))
+	})
+}
+
+// SetValue sets the value of t[id] as if it were set from the teach
+// pendant.
+func (r *Robot) SetValue(t fexcel.Type, id int, value string) error {
+	value, err := fexcel.FormatValue(t, value)
+	if err != nil {
+		return err
+	}
+
+	return r.edit(t, func(src string) (string, error) {
+		return fexcel.ReplaceValue(t, src, id, value)
+	})
+}
+
+// edit applies fn to MDFile(t)
+func (r *Robot) edit(t fexcel.Type, fn func(src string) (string, error)) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	filename := fexcel.MDFile(t)
+	src, ok := r.files[filename]
+	if !ok {
+		return fmt.Errorf("%s not found", filename)
+	}
+
+	src, err := fn(src)
+	if err != nil {
+		return err
+	}
+	r.files[filename] = src
+
+	if r.persist {
+		path := filepath.Join(r.dir, filename)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		return ioutil.WriteFile(path, []byte(src), info.Mode())
+	}
+
+	return nil
+}
+
+func (r *Robot) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/MD/"):
+		src, ok := r.File(strings.TrimPrefix(req.URL.Path, "/MD/"))
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		fmt.Fprint(w, src)
+	case req.URL.Path == "/KAREL/ComSet":
+		r.comSet(w, req)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// comSet handles the KAREL comment tool
+func (r *Robot) comSet(w http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+
+	code, err := strconv.Atoi(params.Get("sFc"))
+	if err != nil {
+		http.Error(w, "invalid sFc", http.StatusBadRequest)
+		return
+	}
+	t, ok := commentTypes[code]
+	vt, setValue := valueTypes[code]
+	if setValue {
+		t = vt
+	} else if !ok {
+		http.Error(w, fmt.Sprintf("unsupported sFc %d", code), http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(params.Get("sIndx"))
+	if err != nil {
+		http.Error(w, "invalid sIndx", http.StatusBadRequest)
+		return
+	}
+
+	if setValue {
+		err = r.SetValue(t, id, params.Get("sValue"))
+	} else {
+		err = r.SetComment(t, id, params.Get("sComment"))
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Fprint(w, "OK")
+}
@@ -0,0 +1,277 @@
+package mock
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onerobotics/fexcel/fexcel"
+)
+
+const backupDir = "../testdata"
+
+func TestRobotSetAndDiff(t *testing.T) {
+	r, err := NewRobot(backupDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	cfg := fexcel.Config{FileConfig: fexcel.FileConfig{
+		Sheet:   "Data",
+		Offset:  1,
+		Numregs: []string{"A2"},
+		Posregs: []string{"D2"},
+		Sregs:   []string{"G2"},
+		Flags:   []string{"J2"},
+		Rins:    []string{"IO:E2"},
+		Ualms:   []string{"Alarms:A2"},
+	}}
+
+	set, err := fexcel.NewSetCommand("../testdata/test.xlsx", cfg, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := set.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for _, count := range result.Counts[s.URL] {
+		total += count
+	}
+	if total != 4 {
+		t.Errorf("Set %d comments, want 4", total)
+	}
+
+	d, err := fexcel.NewDiffCommand("../testdata/test.xlsx", cfg, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := d.CompareAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, res := range results {
+		for _, c := range res.Comparisons {
+			if fexcel.Truncated(c.Want, res.Type) != c.Got[0] {
+				t.Errorf("%s[%d]: Got %q, want %q", res.Type, c.Id, c.Got[0], c.Want)
+			}
+		}
+	}
+
+	// kept in memory
+	src, ok := r.File("POSREG.VA")
+	if !ok || !strings.Contains(src, "[1,1] =   'pr1'") {
+		t.Errorf("PR[1] comment was not changed")
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(backupDir, "posreg.va"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "[1,1] =   'Maintenance'") {
+		t.Errorf("backup directory was changed")
+	}
+}
+
+func TestRobotPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, err := ioutil.ReadFile(filepath.Join(backupDir, "numreg.va"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "numreg.va"), b, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRobot(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	target, err := fexcel.NewTarget(s.URL, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = target.SetComment(fexcel.Numreg, 2, "two words")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not served
+	err = target.SetComment(fexcel.Din, 1, "foo")
+	if err == nil {
+		t.Error("Expected an error for a missing iostate.dg")
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, "numreg.va"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "[2] = 0  'two words'") {
+		t.Errorf("comment was not persisted")
+	}
+
+	err = target.GetComments(fexcel.Numreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Comments[fexcel.Numreg][2] != "two words" {
+		t.Errorf("Got %q, want %q", target.Comments[fexcel.Numreg][2], "two words")
+	}
+}
+
+func TestRobotSetValue(t *testing.T) {
+	r, err := NewRobot(backupDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	target, err := fexcel.NewTarget(s.URL, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []struct {
+		typ   fexcel.Type
+		id    int
+		value string
+	}{
+		{fexcel.Numreg, 2, "70.000000"},
+		{fexcel.Numreg, 3, "-4"},
+		{fexcel.Sreg, 2, "recipe B+C"},
+	}
+
+	for _, v := range values {
+		err = target.SetValue(v.typ, v.id, v.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, v := range values {
+		err = target.GetComments(v.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := target.Values[v.typ][v.id]; got != v.value {
+			t.Errorf("%s[%d] value: Got %q, want %q", v.typ, v.id, got, v.value)
+		}
+	}
+
+	err = target.SetValue(fexcel.Posreg, 1, "1")
+	if err == nil {
+		t.Error("Expected an error for a PR value")
+	}
+}
+
+// copyBackup copies the files of backupDir to a temporary directory
+func copyBackup(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filename := range []string{"numreg.va", "posreg.va", "strreg.va"} {
+		b, err := ioutil.ReadFile(filepath.Join(backupDir, filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(filepath.Join(dir, filename), b, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestRobotSetPositions(t *testing.T) {
+	r, err := NewRobot(backupDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	backup := copyBackup(t)
+	defer os.RemoveAll(backup)
+
+	// the SR comment is planned after the PR position
+	fpath := filepath.Join(backup, "prs.csv")
+	err = ioutil.WriteFile(fpath, []byte("PR,Comment,X,Y,Z,W,P,R,Config,UF,UT,,SR,Comment\n7,Place APTO,9,2,-100,4,5,6,NDB,,,,1,recipe\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := fexcel.Config{FileConfig: fexcel.FileConfig{
+		Sheet:       "prs",
+		Offset:      1,
+		ValueOffset: 2,
+		Posregs:     []string{"A2"},
+		Sregs:       []string{"M2"},
+	}}
+
+	set, err := fexcel.NewSetCommand(fpath, cfg, s.URL, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.Values = true
+
+	result, err := set.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]map[fexcel.Type]int{
+		s.URL:  {fexcel.Posreg: 0, fexcel.Sreg: 1},
+		backup: {fexcel.Posreg: 1, fexcel.Sreg: 1},
+	}
+	for host, counts := range want {
+		for typ, count := range counts {
+			if got := result.Counts[host][typ]; got != count {
+				t.Errorf("%s: Set %d %ss, want %d", host, got, typ, count)
+			}
+		}
+	}
+
+	if w := set.Warnings(); len(w) != 1 || !strings.Contains(w[0], "not setting PR values") {
+		t.Errorf("Expected a warning about PR values on %s. Got %q", s.URL, w)
+	}
+
+	src, _ := r.File("strreg.va")
+	if !strings.Contains(src, "[1] = 'hello'  'recipe'") {
+		t.Errorf("SR[1] comment was not set on %s", s.URL)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(backup, "posreg.va"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "X:     9.000") {
+		t.Errorf("PR[7] position was not set in %s", backup)
+	}
+}
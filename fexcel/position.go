@@ -0,0 +1,346 @@
+package fexcel
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Position is the value of a position register: a Cartesian position
+// with a configuration, or joint angles.
+type Position struct {
+	Joint  bool
+	Axes   []float64 // X, Y, Z, W, P, R or J1, J2, ...; NaN when uninitialized
+	Config string    // e.g. "N U T, 0, 0, 0"; Cartesian only
+	UF, UT string    // frame numbers; blank when unknown
+}
+
+var cartesianAxes = []string{"X", "Y", "Z", "W", "P", "R"}
+
+// spreadsheet columns of a position: six axes, config, UF and UT
+const positionColumns = 9
+
+// the value of an uninitialized position register
+const uninitialized = "Uninitialized"
+
+var (
+	configRegexp   = regexp.MustCompile(`(?i)^\s*([FN])\s*([UD])\s*([TB])\s*(?:,?\s*(-?\d+)\s*,?\s*(-?\d+)\s*,?\s*(-?\d+))?\s*$`)
+	positionRegexp = regexp.MustCompile(`(\w+)=('[^']*'|\S+)`)
+	frameRegexp    = regexp.MustCompile(`^\d+$`)
+)
+
+// formatConfig returns a configuration the way the controller shows it,
+// e.g. "N U T, 0, 0, 0". The turn counts are optional.
+func formatConfig(config string) (string, error) {
+	m := configRegexp.FindStringSubmatch(config)
+	if m == nil {
+		return "", fmt.Errorf("config %q must look like \"N U T, 0, 0, 0\"", config)
+	}
+
+	turns := []string{"0", "0", "0"}
+	if m[4] != "" {
+		turns = m[4:7]
+	}
+
+	return strings.ToUpper(m[1]+" "+m[2]+" "+m[3]) + ", " + strings.Join(turns, ", "), nil
+}
+
+// axisName returns the name of the ith axis, e.g. X or J1
+func (p *Position) axisName(i int) string {
+	if p.Joint {
+		return "J" + strconv.Itoa(i+1)
+	}
+	return cartesianAxes[i]
+}
+
+func formatAxis(f float64) string {
+	if math.IsNaN(f) {
+		return "*"
+	}
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
+
+func parseAxis(s string) (float64, error) {
+	if strings.Trim(s, "*") == "" {
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+// String formats the position with three decimal places like the
+// controller, e.g. X=1.000 Y=2.000 Z=3.000 W=0.000 P=0.000 R=0.000
+// CONFIG='N U T, 0, 0, 0' UF=1 UT=1. A nil position is Uninitialized.
+func (p *Position) String() string {
+	if p == nil {
+		return uninitialized
+	}
+
+	var fields []string
+	for i, a := range p.Axes {
+		fields = append(fields, p.axisName(i)+"="+formatAxis(a))
+	}
+	if !p.Joint {
+		fields = append(fields, "CONFIG='"+p.Config+"'")
+	}
+	if p.UF != "" {
+		fields = append(fields, "UF="+p.UF)
+	}
+	if p.UT != "" {
+		fields = append(fields, "UT="+p.UT)
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// ParsePosition parses a position formatted by String. A blank or
+// Uninitialized position is nil.
+func ParsePosition(s string) (*Position, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == uninitialized {
+		return nil, nil
+	}
+
+	var p Position
+	fields := make(map[string]string)
+	rest := positionRegexp.ReplaceAllStringFunc(s, func(field string) string {
+		m := positionRegexp.FindStringSubmatch(field)
+		fields[strings.ToUpper(m[1])] = strings.Trim(m[2], "'")
+		return ""
+	})
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("invalid position %q", s)
+	}
+
+	_, p.Joint = fields["J1"]
+	if !p.Joint {
+		config, ok := fields["CONFIG"]
+		if !ok {
+			return nil, fmt.Errorf("position %q needs a CONFIG or joint angles", s)
+		}
+		p.Config = config
+		delete(fields, "CONFIG")
+	}
+
+	for i := 0; ; i++ {
+		if !p.Joint && i == len(cartesianAxes) {
+			break
+		}
+
+		v, ok := fields[p.axisName(i)]
+		if !ok {
+			if p.Joint {
+				break
+			}
+			return nil, fmt.Errorf("position %q has no %s", s, p.axisName(i))
+		}
+
+		a, err := parseAxis(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q of position %q is not a number", p.axisName(i), v, s)
+		}
+		p.Axes = append(p.Axes, a)
+		delete(fields, p.axisName(i))
+	}
+
+	p.UF, p.UT = fields["UF"], fields["UT"]
+	delete(fields, "UF")
+	delete(fields, "UT")
+
+	if len(fields) > 0 {
+		var names []string
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("position %q has unknown fields: %s", s, strings.Join(names, ", "))
+	}
+
+	return &p, p.validate()
+}
+
+func (p *Position) validate() error {
+	if !p.Joint {
+		config, err := formatConfig(p.Config)
+		if err != nil {
+			return err
+		}
+		p.Config = config
+	}
+
+	for _, frame := range []string{p.UF, p.UT} {
+		if frame != "" && !frameRegexp.MatchString(frame) {
+			return fmt.Errorf("frame %q must be a number", frame)
+		}
+	}
+
+	return nil
+}
+
+// positionFromCells reads a position from its spreadsheet columns: X (or
+// J1) through R (or J6), config, UF and UT. The config of joint
+// positions is "Joint". Blank cells are nil.
+func positionFromCells(cells []string) (*Position, error) {
+	blank := true
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+		if cells[i] != "" {
+			blank = false
+		}
+	}
+	if blank {
+		return nil, nil
+	}
+
+	axes, config := cells[:len(cartesianAxes)], cells[len(cartesianAxes)]
+	p := Position{Joint: strings.EqualFold(config, "joint"), Config: config, UF: cells[7], UT: cells[8]}
+	if p.Joint {
+		p.Config = ""
+	}
+
+	for i, v := range axes {
+		if v == "" {
+			// robots with fewer axes leave the rest blank
+			if p.Joint && i > 0 && strings.Join(axes[i:], "") == "" {
+				break
+			}
+			return nil, fmt.Errorf("%s is blank", p.axisName(i))
+		}
+
+		a, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q is not a number", p.axisName(i), v)
+		}
+		p.Axes = append(p.Axes, a)
+	}
+
+	return &p, p.validate()
+}
+
+// cells returns the spreadsheet columns of the position
+func (p *Position) cells() []string {
+	cells := make([]string, positionColumns)
+	for i, a := range p.Axes {
+		if i < len(cartesianAxes) {
+			cells[i] = formatAxis(a)
+		}
+	}
+
+	cells[6] = p.Config
+	if p.Joint {
+		cells[6] = "Joint"
+	}
+	cells[7], cells[8] = p.UF, p.UT
+
+	return cells
+}
+
+// DefaultTolerance is used for the axes a Tolerance doesn't list. The
+// controller stores positions with three decimal places.
+const DefaultTolerance = 0.001
+
+// A Tolerance is the largest difference allowed between two positions
+// by axis (X, Y, Z, W, P, R or J1, J2, ...). J sets the tolerance of
+// every joint that isn't listed on its own.
+type Tolerance map[string]float64
+
+var toleranceAxisRegexp = regexp.MustCompile(`(?i)^([XYZWPR]|J\d*)$`)
+
+// ParseTolerance parses tolerances by axis, e.g. {"X": "0.5"}
+func ParseTolerance(m map[string]string) (Tolerance, error) {
+	t := make(Tolerance)
+	for axis, s := range m {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("tolerance %q for %s is not a number", s, axis)
+		}
+		t[axis] = f
+	}
+
+	return t, t.Validate()
+}
+
+func (t Tolerance) Validate() error {
+	for axis, f := range t {
+		if !toleranceAxisRegexp.MatchString(axis) {
+			return fmt.Errorf("unknown tolerance axis %q (must be X, Y, Z, W, P, R, J or J1, J2, ...)", axis)
+		}
+		if f < 0 || math.IsNaN(f) {
+			return fmt.Errorf("tolerance for %s must not be negative", axis)
+		}
+	}
+
+	return nil
+}
+
+// For returns the tolerance of an axis. Axes are matched
+// case-insensitively since config files lowercase them.
+func (t Tolerance) For(axis string) float64 {
+	var joints *float64
+	for name, f := range t {
+		if strings.EqualFold(name, axis) {
+			return f
+		}
+		if strings.EqualFold(name, "J") {
+			f := f
+			joints = &f
+		}
+	}
+
+	if joints != nil && strings.HasPrefix(axis, "J") {
+		return *joints
+	}
+
+	return DefaultTolerance
+}
+
+// Within reports whether p and q are the same kind of position with the
+// same configuration and every axis within the tolerance. Frames are
+// only compared when both positions have them.
+func (p *Position) Within(q *Position, t Tolerance) bool {
+	if p == nil || q == nil {
+		return p == q
+	}
+
+	if p.Joint != q.Joint || p.Config != q.Config || len(p.Axes) != len(q.Axes) {
+		return false
+	}
+
+	if p.UF != "" && q.UF != "" && p.UF != q.UF {
+		return false
+	}
+	if p.UT != "" && q.UT != "" && p.UT != q.UT {
+		return false
+	}
+
+	for i := range p.Axes {
+		// values are rounded to three decimal places, so allow for
+		// floating point error
+		if !(math.Abs(p.Axes[i]-q.Axes[i]) <= t.For(p.axisName(i))+1e-9) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// valuesMatch compares two values formatted by FormatValue. Positions
+// only have to be within the tolerance.
+func valuesMatch(typ Type, want, got string, t Tolerance) bool {
+	if typ != Posreg || want == got {
+		return want == got
+	}
+
+	p, err := ParsePosition(want)
+	if err != nil {
+		return false
+	}
+	q, err := ParsePosition(got)
+	if err != nil {
+		return false
+	}
+
+	return p.Within(q, t)
+}
@@ -0,0 +1,481 @@
+package fexcel
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"", "Uninitialized"},
+		{"Uninitialized", "Uninitialized"},
+		{"X=1 Y=2 Z=-100 W=4 P=5 R=6 CONFIG='N D B, 0, 0, 0'", "X=1.000 Y=2.000 Z=-100.000 W=4.000 P=5.000 R=6.000 CONFIG='N D B, 0, 0, 0'"},
+		{"config='fut' x=1 y=2 z=3 w=0 p=0 r=0 uf=1 ut=2", "X=1.000 Y=2.000 Z=3.000 W=0.000 P=0.000 R=0.000 CONFIG='F U T, 0, 0, 0' UF=1 UT=2"},
+		{"X=1 Y=2 Z=3 W=0 P=0 R=0 CONFIG='N U T, -1, 0, 1'", "X=1.000 Y=2.000 Z=3.000 W=0.000 P=0.000 R=0.000 CONFIG='N U T, -1, 0, 1'"},
+		{"J1=0 J2=-90.5 J3=0 J4=0 J5=0 J6=0 J7=1000", "J1=0.000 J2=-90.500 J3=0.000 J4=0.000 J5=0.000 J6=0.000 J7=1000.000"},
+		{"J1=* J2=1", "J1=* J2=1.000"},
+	}
+
+	for _, test := range tests {
+		p, err := ParsePosition(test.s)
+		if err != nil {
+			t.Errorf("ParsePosition(%q): %s", test.s, err)
+			continue
+		}
+
+		if got := p.String(); got != test.want {
+			t.Errorf("ParsePosition(%q): Got %q, want %q", test.s, got, test.want)
+		}
+	}
+
+	errors := []struct {
+		s   string
+		err string
+	}{
+		{"1 2 3", `invalid position "1 2 3"`},
+		{"X=1 Y=2 Z=3 W=0 P=0 CONFIG='NUT'", `position "X=1 Y=2 Z=3 W=0 P=0 CONFIG='NUT'" has no R`},
+		{"X=1 Y=2 Z=3 W=0 P=0 R=a CONFIG='NUT'", `R "a" of position "X=1 Y=2 Z=3 W=0 P=0 R=a CONFIG='NUT'" is not a number`},
+		{"X=1 Y=2 Z=3 W=0 P=0 R=0 CONFIG='up'", `config "up" must look like "N U T, 0, 0, 0"`},
+		{"J1=0 J2=0 X=1", `position "J1=0 J2=0 X=1" has unknown fields: X`},
+		{"J1=0 UF=a", `frame "a" must be a number`},
+	}
+
+	for _, test := range errors {
+		_, err := ParsePosition(test.s)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("ParsePosition(%q): Got error %v, want %q", test.s, err, test.err)
+		}
+	}
+}
+
+func TestPositionCells(t *testing.T) {
+	tests := []struct {
+		cells []string
+		want  string
+	}{
+		{[]string{"", "", "", "", "", "", "", "", ""}, "Uninitialized"},
+		{[]string{"1", "2", "3", "4", "5", "6", "NUT 0 0 1", "1", "2"}, "X=1.000 Y=2.000 Z=3.000 W=4.000 P=5.000 R=6.000 CONFIG='N U T, 0, 0, 1' UF=1 UT=2"},
+		{[]string{"10", "20", "30", "40", "", "", "joint", "", ""}, "J1=10.000 J2=20.000 J3=30.000 J4=40.000"},
+	}
+
+	for _, test := range tests {
+		p, err := positionFromCells(test.cells)
+		if err != nil {
+			t.Errorf("positionFromCells(%q): %s", test.cells, err)
+			continue
+		}
+
+		if got := p.String(); got != test.want {
+			t.Errorf("positionFromCells(%q): Got %q, want %q", test.cells, got, test.want)
+		}
+
+		if p == nil {
+			continue
+		}
+
+		// and back again
+		q, err := positionFromCells(p.cells())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.String() != test.want {
+			t.Errorf("cells of %q: Got %q", test.want, q.String())
+		}
+	}
+
+	errors := []struct {
+		cells []string
+		err   string
+	}{
+		{[]string{"1", "2", "", "4", "5", "6", "NUT", "", ""}, "Z is blank"},
+		{[]string{"1", "", "3", "", "", "", "Joint", "", ""}, "J2 is blank"},
+		{[]string{"1", "2", "3", "4", "5", "x", "NUT", "", ""}, `R "x" is not a number`},
+		{[]string{"1", "2", "3", "4", "5", "6", "", "", ""}, `config "" must look like "N U T, 0, 0, 0"`},
+	}
+
+	for _, test := range errors {
+		_, err := positionFromCells(test.cells)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("positionFromCells(%q): Got error %v, want %q", test.cells, err, test.err)
+		}
+	}
+}
+
+func TestTolerance(t *testing.T) {
+	tol, err := ParseTolerance(map[string]string{"x": "0.5", "J": "0.1", "J2": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for axis, want := range map[string]float64{"X": 0.5, "Y": DefaultTolerance, "J1": 0.1, "J2": 1} {
+		if got := tol.For(axis); got != want {
+			t.Errorf("For(%s): Got %f, want %f", axis, got, want)
+		}
+	}
+
+	errors := map[string]string{
+		"Q":  `unknown tolerance axis "Q" (must be X, Y, Z, W, P, R, J or J1, J2, ...)`,
+		"JX": `unknown tolerance axis "JX" (must be X, Y, Z, W, P, R, J or J1, J2, ...)`,
+	}
+	for axis, want := range errors {
+		_, err := ParseTolerance(map[string]string{axis: "1"})
+		if err == nil || err.Error() != want {
+			t.Errorf("ParseTolerance(%s): Got error %v, want %q", axis, err, want)
+		}
+	}
+
+	_, err = ParseTolerance(map[string]string{"X": "-1"})
+	if err == nil || err.Error() != "tolerance for X must not be negative" {
+		t.Errorf("Expected a negative tolerance error. Got %v", err)
+	}
+
+	p := &Position{Axes: []float64{1, 2, 3, 0, 0, 0}, Config: "N U T, 0, 0, 0", UF: "1"}
+	tests := []struct {
+		q    *Position
+		want bool
+	}{
+		{&Position{Axes: []float64{1.4, 2, 3, 0, 0, 0}, Config: "N U T, 0, 0, 0"}, true},
+		{&Position{Axes: []float64{1.6, 2, 3, 0, 0, 0}, Config: "N U T, 0, 0, 0"}, false},
+		{&Position{Axes: []float64{1, 2.001, 3, 0, 0, 0}, Config: "N U T, 0, 0, 0"}, true},
+		{&Position{Axes: []float64{1, 2.002, 3, 0, 0, 0}, Config: "N U T, 0, 0, 0"}, false},
+		{&Position{Axes: []float64{1, 2, 3, 0, 0, math.NaN()}, Config: "N U T, 0, 0, 0"}, false},
+		{&Position{Axes: []float64{1, 2, 3, 0, 0, 0}, Config: "F U T, 0, 0, 0"}, false},
+		{&Position{Axes: []float64{1, 2, 3, 0, 0, 0}, Config: "N U T, 0, 0, 0", UF: "2"}, false},
+		{&Position{Axes: []float64{1, 2, 3, 0, 0, 0}, Joint: true}, false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		if got := p.Within(test.q, tol); got != test.want {
+			t.Errorf("Within(%s): Got %t, want %t", test.q, got, test.want)
+		}
+	}
+}
+
+func TestPositionRegisters(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/posreg.va")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posregs, err := parsePositionRegisters(string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posregs) != 100 {
+		t.Fatalf("Got %d PRs, want 100", len(posregs))
+	}
+
+	if r := posregs[6]; r.Id != 7 || r.Comment != "Place APTO" || r.Position.String() != "X=1.000 Y=2.000 Z=-100.000 W=4.000 P=5.000 R=6.000 CONFIG='N D B, 0, 0, 0'" {
+		t.Errorf("Bad PR[7]: %v %s", r, r.Position)
+	}
+	if r := posregs[99]; r.Id != 100 || r.Position != nil {
+		t.Errorf("Bad PR[100]: %v", r)
+	}
+
+	// writing a position back leaves the file as it was
+	src := string(b)
+	for _, id := range []int{7, 20, 100} {
+		got, err := ReplacePosition(src, id, posregs[id-1].Position)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != src {
+			t.Errorf("PR[%d] was not rewritten as it was", id)
+		}
+	}
+
+	joint := &Position{Joint: true, Axes: []float64{0, -90, 0, 0, 0, 180}}
+	got, err := ReplacePosition(src, 100, joint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "    [1,100] =   ''   Group: 1\n  J1 =     0.000 deg   J2 =   -90.000 deg   J3 =     0.000 deg \n  J4 =     0.000 deg   J5 =     0.000 deg   J6 =   180.000 deg \n\n[*POSREG*]$MAXPREGNUM"
+	if !strings.Contains(got, want) {
+		t.Errorf("PR[100] was not set to a joint position:\n%s", got[strings.Index(got, "[1,100]"):])
+	}
+
+	posregs, err = parsePositionRegisters(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := posregs[99].Position; !joint.Within(p, nil) {
+		t.Errorf("Got PR[100] %s, want %s", p, joint)
+	}
+
+	_, err = ReplacePosition(src, 101, nil)
+	if err == nil || err.Error() != "PR[101] not found in posreg.va" {
+		t.Errorf("Expected a not found error. Got %v", err)
+	}
+}
+
+// positionsCSV holds PR comments that match testdata
+const positionsCSV = `PR,Comment,X,Y,Z,W,P,R,Config,UF,UT
+6,ZERO,,,,,,,,,
+7,Place APTO,1.0008,2,-100,4,5,6,N D B,1,1
+8,Place RTTO,0,10,20,30,40,50,Joint,,
+20,Outfeed Approach,1,2,-10.5,4,5,6,NDB,,
+`
+
+var positionsConfig = Config{FileConfig: FileConfig{
+	Sheet:       "prs",
+	Offset:      1,
+	ValueOffset: 2,
+	Posregs:     []string{"A2"},
+}}
+
+func TestDiffPositions(t *testing.T) {
+	dir := tempCSV(t, map[string]string{"prs.csv": positionsCSV})
+	defer os.RemoveAll(dir)
+
+	cfg := positionsConfig
+	for _, test := range []struct {
+		tolerance   Tolerance
+		differences []int
+	}{
+		{nil, []int{8, 20}},
+		{Tolerance{"Z": 0.5}, []int{8}},
+		{Tolerance{"X": 0.0001}, []int{7, 8, 20}},
+	} {
+		cfg.Tolerance = test.tolerance
+		d, err := NewDiffCommand(filepath.Join(dir, "prs.csv"), cfg, "testdata")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		results, err := d.CompareAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 || results[0].Differences() != 0 || !results[1].Values {
+			t.Fatalf("Expected equal comments and a values result. Got %v", results)
+		}
+
+		var differences []int
+		for _, c := range results[1].Comparisons {
+			if !c.Equal() {
+				differences = append(differences, c.Id)
+			}
+		}
+		if len(differences) != len(test.differences) {
+			t.Errorf("tolerance %v: Got differences in %v, want %v", test.tolerance, differences, test.differences)
+			continue
+		}
+		for i := range differences {
+			if differences[i] != test.differences[i] {
+				t.Errorf("tolerance %v: Got differences in %v, want %v", test.tolerance, differences, test.differences)
+			}
+		}
+	}
+
+	cfg.Tolerance = Tolerance{"Q": 1}
+	_, err := NewDiffCommand(filepath.Join(dir, "prs.csv"), cfg, "testdata")
+	if err == nil {
+		t.Error("Expected an unknown axis error")
+	}
+}
+
+func TestSetPositions(t *testing.T) {
+	dir := tempBackup(t)
+	defer os.RemoveAll(dir)
+
+	csvDir := tempCSV(t, map[string]string{"prs.csv": positionsCSV})
+	defer os.RemoveAll(csvDir)
+
+	s, err := NewSetCommand(filepath.Join(csvDir, "prs.csv"), positionsConfig, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Values = true
+	s.SnapshotDir = filepath.Join(csvDir, "snapshots")
+
+	result, err := s.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Counts[dir][Posreg]; got != 2 {
+		t.Errorf("Set %d PR positions, want 2", got)
+	}
+
+	target, err := NewTarget(dir, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = target.GetValues(Posreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[int]string{
+		6:  "Uninitialized",
+		7:  "X=1.000 Y=2.000 Z=-100.000 W=4.000 P=5.000 R=6.000 CONFIG='N D B, 0, 0, 0'",
+		8:  "J1=0.000 J2=10.000 J3=20.000 J4=30.000 J5=40.000 J6=50.000",
+		20: "X=1.000 Y=2.000 Z=-10.500 W=4.000 P=5.000 R=6.000 CONFIG='N D B, 0, 0, 0'",
+	}
+	for id, value := range want {
+		if got := target.Values[Posreg][id]; got != value {
+			t.Errorf("PR[%d]: Got %q, want %q", id, got, value)
+		}
+	}
+
+	r, err := NewRollbackCommand(s.SnapshotPath, Config{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orig, err := ioutil.ReadFile("testdata/posreg.va")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "posreg.va"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(orig) {
+		t.Error("posreg.va was not restored")
+	}
+}
+
+func TestManifestPositions(t *testing.T) {
+	dir := tempCSV(t, map[string]string{"prs.csv": positionsCSV})
+	defer os.RemoveAll(dir)
+
+	f, err := OpenFile(filepath.Join(dir, "prs.csv"), positionsConfig.FileConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := f.Definitions(Posreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := f.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mpath := filepath.Join(dir, "robot.yaml")
+	err = m.Save(mpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := OpenFile(mpath, FileConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := manifest.Definitions(Posreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("Got %v, want %v", got[i], want[i])
+		}
+	}
+}
+
+func TestPositionsHTTP(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/MD/posreg.va":
+			rw.Write([]byte("[*POSREG*]$POSREG  Storage: CMOS  Access: RW  : ARRAY[1,2] OF Position Reg\r\n    [1,1] =   'home'   Group: 1\r\n  J1 =     0.000 deg   J2 =   -90.000 deg   J3 =     0.000 deg \r\n  J4 =     0.000 deg   J5 =     0.000 deg   J6 =   180.000 deg \r\n    [1,2] =   '' Uninitialized\r\n    [1,3] =   'odd'   Group: 1\r\n  E1 =     0.000 mm \r\n"))
+		default:
+			http.Error(rw, "Not implemented", http.StatusNotImplemented)
+		}
+	}))
+	defer s.Close()
+
+	target, err := NewTarget(s.URL, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = target.GetComments(Posreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := target.Comments[Posreg][1]; got != "home" {
+		t.Errorf("Bad comment for PR[1]. Got %q, want %q", got, "home")
+	}
+
+	// positions are only parsed when they are asked for, and one that
+	// can't be doesn't stop the others
+	err = target.GetValues(Posreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := target.ValueErrors[Posreg][3]; !ok {
+		t.Errorf("Expected PR[3] to be unreadable. Got %q", target.Values[Posreg][3])
+	}
+	if got, want := target.Values[Posreg][1], "J1=0.000 J2=-90.000 J3=0.000 J4=0.000 J5=0.000 J6=180.000"; got != want {
+		t.Errorf("Bad position for PR[1]. Got %q, want %q", got, want)
+	}
+	if got := target.Values[Posreg][2]; got != "Uninitialized" {
+		t.Errorf("Bad position for PR[2]. Got %q", got)
+	}
+
+	err = target.SetValue(Posreg, 2, "J1=0.000")
+	if err == nil || !strings.Contains(err.Error(), "only in backup directories") {
+		t.Errorf("Expected an error setting a PR over HTTP. Got %v", err)
+	}
+}
+
+func TestSetUnreadablePosition(t *testing.T) {
+	dir := tempBackup(t)
+	defer os.RemoveAll(dir)
+
+	// PR[7] with a body we don't know
+	path := filepath.Join(dir, "posreg.va")
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := ReplacePosition(string(b), 7, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src = strings.Replace(src, "'Place APTO' Uninitialized\n", "'Place APTO'   Group: 1\n  E1 =     0.000 mm \n", 1)
+	err = ioutil.WriteFile(path, []byte(src), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csvDir := tempCSV(t, map[string]string{"prs.csv": positionsCSV})
+	defer os.RemoveAll(csvDir)
+
+	s, err := NewSetCommand(filepath.Join(csvDir, "prs.csv"), positionsConfig, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Values = true
+
+	plans, err := s.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range plans[0].Changes {
+		if c.Value && c.Id == 7 {
+			t.Errorf("Planned a change to unreadable PR[7]: %v", c)
+		}
+	}
+	if w := s.Warnings(); len(w) != 1 || !strings.Contains(w[0], "not setting PR[7] value") {
+		t.Errorf("Expected a warning about PR[7]. Got %q", w)
+	}
+}
@@ -0,0 +1,147 @@
+package fexcel
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+// A Puller updates an existing spreadsheet with a target's comments.
+type Puller struct {
+	file   *File
+	target *Target
+	append bool // append ids that are missing from the spreadsheet
+}
+
+func NewPuller(path string, cfg Config, targetPath string, appendMissing bool) (*Puller, error) {
+	f, err := OpenFile(path, cfg.FileConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := NewTarget(targetPath, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Puller{file: f, target: t, append: appendMissing}, nil
+}
+
+// Pull updates the comment cells of the ids listed at each location
+// (and optionally appends missing ids) before saving the spreadsheet.
+// Nothing else in the workbook is changed.
+func (p *Puller) Pull(w io.Writer) error {
+	fmt.Fprintf(w, "Updating file: %s\n", p.file.path)
+
+	var types []Type
+	for t := range p.file.Locations {
+		if t != Constant {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	for _, t := range types {
+		fmt.Fprintf(w, "Reading target %s comments\n", t)
+		err := p.target.GetComments(t)
+		if err != nil {
+			return err
+		}
+
+		defs, cells, err := p.file.definitions(t)
+		if err != nil {
+			return err
+		}
+
+		listed := make(map[int]bool)
+		updated := 0
+		for i, def := range defs {
+			listed[def.Id] = true
+
+			comment, ok := p.target.Comments[t][def.Id]
+			if !ok {
+				continue
+			}
+
+			// keep long comments that the controller has truncated
+			if Truncated(def.Comment, t) == comment {
+				continue
+			}
+
+			// a single cell can't hold the comments of an id range
+			if cells[i].ranged {
+				continue
+			}
+
+			err = p.file.setComment(cells[i], comment)
+			if err != nil {
+				return err
+			}
+			updated++
+		}
+		fmt.Fprintf(w, "Updated %d %s %s\n", updated, t, Pluralize("comment", updated))
+
+		if !p.append {
+			continue
+		}
+
+		var missing []int
+		for id, comment := range p.target.Comments[t] {
+			if !listed[id] && comment != "" {
+				missing = append(missing, id)
+			}
+		}
+		sort.Ints(missing)
+
+		// missing ids are appended to the last location, starting at
+		// the first blank row after its listed ids
+		locs := p.file.Locations[t]
+		location := locs[len(locs)-1]
+		if location.End != 0 && len(missing) > 0 {
+			return fmt.Errorf("cannot append %ss past the end of %s", t, location)
+		}
+
+		col, row, err := excelize.CellNameToCoordinates(location.Axis)
+		if err != nil {
+			return err
+		}
+		offset := p.file.offsetFor(location)
+
+		for _, c := range cells {
+			if c.loc == location && c.row >= row {
+				row = c.row + 1
+			}
+		}
+
+		for _, id := range missing {
+			for _, c := range []int{col, col + offset} {
+				s, err := p.file.readString(location.Sheet, c, row)
+				if err != nil {
+					return err
+				}
+				if s != "" {
+					axis, _ := excelize.CoordinatesToCellName(c, row)
+					return fmt.Errorf("cannot append %s[%d]: [%s]%s is not empty", t, id, location.Sheet, axis)
+				}
+			}
+
+			err = p.file.SetValue(location.Sheet, col, row, id)
+			if err != nil {
+				return err
+			}
+
+			err = p.file.SetValue(location.Sheet, col+offset, row, p.target.Comments[t][id])
+			if err != nil {
+				return err
+			}
+
+			row++
+		}
+		fmt.Fprintf(w, "Appended %d %s\n", len(missing), Pluralize(t.String(), len(missing)))
+	}
+
+	fmt.Fprintln(w, "Saving file.")
+	return p.file.Save()
+}
@@ -0,0 +1,118 @@
+package fexcel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copies the test spreadsheet to a temporary directory
+func tempSpreadsheet(t *testing.T) (dir, fpath string) {
+	dir, err := ioutil.TempDir("", "fexcel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "test.xlsx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fpath = filepath.Join(dir, "test.xlsx")
+	err = ioutil.WriteFile(fpath, b, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, fpath
+}
+
+func TestPull(t *testing.T) {
+	dir, fpath := tempSpreadsheet(t)
+	defer os.RemoveAll(dir)
+
+	cfg := Config{FileConfig: FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2"}, Posregs: []string{"D2"}, Constants: []string{"M2"}}}
+
+	p, err := NewPuller(fpath, cfg, "testdata", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.Pull(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenFile(fpath, FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2"}, Posregs: []string{"D2"}, Constants: []string{"M2"}, Dins: []string{"IO:A2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	numregs, err := f.Definitions(Numreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(numregs) != 5 {
+		t.Errorf("Got %d numregs, want 5", len(numregs))
+	}
+	// truncated on the controller, so the spreadsheet wins
+	if numregs[0].Comment != "this is an extremely long comment" {
+		t.Errorf("Bad R[1] comment: %q", numregs[0].Comment)
+	}
+
+	posregs, err := f.Definitions(Posreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posregs[0].Comment != "Maintenance" || posregs[1].Comment != "pr2" {
+		t.Errorf("Bad posreg comments: %v", posregs)
+	}
+
+	// untouched
+	constants, err := f.Constants()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if constants["FOO"] != "bar" {
+		t.Errorf("Bad constant. Got %q, want %q", constants["FOO"], "bar")
+	}
+	dins, err := f.Definitions(Din)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dins) != 3 || dins[2].Comment != "din3" {
+		t.Errorf("Bad dins: %v", dins)
+	}
+
+	// now append the missing numregs
+	cfg.FileConfig = FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2"}}
+	p, err = NewPuller(fpath, cfg, "testdata", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.Pull(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewDiffCommand(fpath, cfg, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparisons, err := d.Compare(Numreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every numreg with a comment
+	if len(comparisons) != 117 {
+		t.Errorf("Got %d numregs, want 117", len(comparisons))
+	}
+	for _, c := range comparisons[1:] {
+		if !c.Equal() {
+			t.Errorf("R[%d] not equal: %v", c.Id, c)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package fexcel
+
+import (
+	"fmt"
+)
+
+// A RollbackCommand restores the comments recorded in a Snapshot.
+type RollbackCommand struct {
+	snapshot *Snapshot
+	plan     *Plan
+	target   *Target
+}
+
+func NewRollbackCommand(snapshotPath string, cfg Config, host string) (*RollbackCommand, error) {
+	snapshot, err := ReadSnapshot(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := snapshot.PlanFor(host)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := NewTarget(host, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RollbackCommand{snapshot: snapshot, plan: plan, target: t}, nil
+}
+
+// Plan returns the changes required to restore the snapshot. Comments
+// that already match the snapshot are left alone.
+func (r *RollbackCommand) Plan() (*Plan, error) {
+	p := Plan{Host: r.target.Name, Changes: []Change{}}
+
+	fetched, fetchedValues := make(map[Type]bool), make(map[Type]bool)
+	for _, c := range r.plan.Changes {
+		if !fetched[c.Type] {
+			err := r.target.GetComments(c.Type)
+			if err != nil {
+				return nil, err
+			}
+			fetched[c.Type] = true
+		}
+
+		got := r.target.Comments[c.Type][c.Id]
+		if c.Value {
+			if !fetchedValues[c.Type] {
+				err := r.target.GetValues(c.Type)
+				if err != nil {
+					return nil, err
+				}
+				fetchedValues[c.Type] = true
+			}
+			got = r.target.Values[c.Type][c.Id]
+		}
+		if got == c.Old {
+			continue
+		}
+
+		p.Changes = append(p.Changes, Change{Type: c.Type, Id: c.Id, Old: got, New: c.Old, Value: c.Value})
+	}
+
+	return &p, nil
+}
+
+// Execute restores the snapshot and returns the number of comments
+// that were changed.
+func (r *RollbackCommand) Execute() (count int, err error) {
+	p, err := r.Plan()
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if saveErr := r.target.Save(); err == nil {
+			err = saveErr
+		}
+	}()
+
+	for _, c := range p.Changes {
+		err = c.apply(r.target)
+		if err != nil {
+			return count, fmt.Errorf("failed to restore %s: %s", c, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+func (r *RollbackCommand) Snapshot() *Snapshot {
+	return r.snapshot
+}
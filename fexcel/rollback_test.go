@@ -0,0 +1,100 @@
+package fexcel
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRollback(t *testing.T) {
+	dir := tempBackup(t)
+	defer os.RemoveAll(dir)
+
+	snapshotDir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(snapshotDir)
+
+	cfg := Config{FileConfig: FileConfig{
+		Sheet:   "Data",
+		Offset:  1,
+		Posregs: []string{"D2"},
+		Flags:   []string{"J2"},
+		Rins:    []string{"IO:E2"},
+		Ualms:   []string{"Alarms:A2"},
+	}}
+
+	s, err := NewSetCommand("./testdata/test.xlsx", cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SnapshotDir = snapshotDir
+
+	_, err = s.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.SnapshotPath == "" {
+		t.Fatal("Expected a snapshot")
+	}
+
+	snapshot, err := ReadSnapshot(s.SnapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Len() != 4 {
+		t.Errorf("Got %d changes in snapshot. Want 4", snapshot.Len())
+	}
+
+	_, err = NewRollbackCommand(s.SnapshotPath, Config{}, "testdata")
+	if err == nil || err.Error() != "snapshot does not contain \"testdata\"" {
+		t.Errorf("Expected an unknown host error. Got %v", err)
+	}
+
+	r, err := NewRollbackCommand(s.SnapshotPath, Config{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := r.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("Restored %d comments. Want 4", count)
+	}
+
+	// everything should be back to the way it was
+	for _, filename := range []string{"posreg.va", "sysvars.va", "iostate.dg"} {
+		orig, err := ioutil.ReadFile(filepath.Join("testdata", filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(orig, got) {
+			t.Errorf("%s was not restored", filename)
+		}
+	}
+
+	// a second rollback has nothing to do
+	r, err = NewRollbackCommand(s.SnapshotPath, Config{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err = r.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("Restored %d comments. Want 0", count)
+	}
+}
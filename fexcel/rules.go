@@ -0,0 +1,127 @@
+package fexcel
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rules are the naming conventions comments must follow. Every comment
+// of a type must match its pattern, and every comment on a sheet must
+// start with one of the sheet's prefixes. Blank comments are ignored.
+type Rules struct {
+	Patterns map[Type]*regexp.Regexp
+	Prefixes map[string][]string // by sheet
+}
+
+// NewRules compiles the patterns, which are keyed by type name (e.g.
+// DI). Sheet names and type names are case-insensitive.
+func NewRules(patterns map[string]string, prefixes map[string][]string) (*Rules, error) {
+	r := Rules{
+		Patterns: make(map[Type]*regexp.Regexp),
+		Prefixes: make(map[string][]string),
+	}
+
+	for name, pattern := range patterns {
+		t, err := ParseType(strings.ToUpper(name))
+		if err != nil {
+			return nil, err
+		}
+
+		r.Patterns[t], err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule for %ss: %s", t, err)
+		}
+	}
+
+	for sheet, p := range prefixes {
+		r.Prefixes[strings.ToLower(sheet)] = p
+	}
+
+	return &r, nil
+}
+
+// Check returns the rules the comment breaks
+func (r *Rules) Check(t Type, sheet string, comment string) (broken []string) {
+	if comment == "" {
+		return nil
+	}
+
+	if re, ok := r.Patterns[t]; ok && !re.MatchString(comment) {
+		broken = append(broken, fmt.Sprintf("does not match the %s rule %q", t, re))
+	}
+
+	if prefixes, ok := r.Prefixes[strings.ToLower(sheet)]; ok && len(prefixes) > 0 {
+		allowed := false
+		for _, p := range prefixes {
+			if strings.HasPrefix(comment, p) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			broken = append(broken, fmt.Sprintf("does not start with a prefix allowed on sheet %q (%s)", sheet, strings.Join(prefixes, ", ")))
+		}
+	}
+
+	return broken
+}
+
+// A Violation is a comment in the spreadsheet that breaks a rule
+type Violation struct {
+	Cell    string // e.g. [IO]B2
+	Type    Type
+	Id      int
+	Comment string
+	Msg     string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: comment %q for %s[%d] %s", v.Cell, v.Comment, v.Type, v.Id, v.Msg)
+}
+
+// CheckRules returns every comment in the spreadsheet that breaks a
+// rule, in type order.
+func (f *File) CheckRules(r *Rules) ([]Violation, error) {
+	var types []Type
+	for t := range f.Locations {
+		if t != Constant {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	var violations []Violation
+	for _, t := range types {
+		defs, cells, err := f.definitions(t)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, d := range defs {
+			for _, msg := range r.Check(t, cells[i].loc.Sheet, d.Comment) {
+				violations = append(violations, Violation{f.commentCell(cells[i]), t, d.Id, d.Comment, msg})
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// A RuleError lists the comments that were not set because they break
+// the rules.
+type RuleError struct {
+	Violations []Violation
+}
+
+func (e *RuleError) Error() string {
+	n := len(e.Violations)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "naming rules broken by %d %s:", n, Pluralize("comment", n))
+	for _, v := range e.Violations {
+		fmt.Fprintf(&b, "\n  %s", v)
+	}
+	return b.String()
+}
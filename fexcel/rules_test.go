@@ -0,0 +1,116 @@
+package fexcel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRulesCheck(t *testing.T) {
+	r, err := NewRules(map[string]string{
+		"di": `^ST\d+_[A-Z0-9]+_[A-Z0-9_]+$`,
+		"R":  `^[A-Z][A-Za-z0-9]*$`,
+	}, map[string][]string{
+		"io": {"ST10_", "ST20_"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		t       Type
+		sheet   string
+		comment string
+		broken  []string
+	}{
+		{Din, "IO", "ST10_CLAMP1_CLOSED", nil},
+		{Din, "IO", "", nil},
+		{Din, "IO", "ST30_CLAMP1_CLOSED", []string{`does not start with a prefix allowed on sheet "IO" (ST10_, ST20_)`}},
+		{Din, "IO", "clamp closed", []string{
+			`does not match the DI rule "^ST\\d+_[A-Z0-9]+_[A-Z0-9_]+$"`,
+			`does not start with a prefix allowed on sheet "IO" (ST10_, ST20_)`,
+		}},
+		{Din, "Other", "ST30_CLAMP1_CLOSED", nil},
+		{Numreg, "Data", "PartCount", nil},
+		{Numreg, "Data", "part count", []string{`does not match the R rule "^[A-Z][A-Za-z0-9]*$"`}},
+		{Posreg, "Data", "anything goes", nil},
+	}
+
+	for _, test := range tests {
+		broken := r.Check(test.t, test.sheet, test.comment)
+		if !reflect.DeepEqual(broken, test.broken) {
+			t.Errorf("Check(%s, %q, %q): Got %q, want %q", test.t, test.sheet, test.comment, broken, test.broken)
+		}
+	}
+}
+
+func TestNewRulesErrors(t *testing.T) {
+	_, err := NewRules(map[string]string{"XYZ": "."}, nil)
+	if err == nil || err.Error() != `unknown type "XYZ"` {
+		t.Errorf("Bad error. Got %v, want %q", err, `unknown type "XYZ"`)
+	}
+
+	_, err = NewRules(map[string]string{"DI": "("}, nil)
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSetCommandRules(t *testing.T) {
+	dir := tempBackup(t)
+	defer os.RemoveAll(dir)
+
+	cfg := Config{FileConfig: FileConfig{Sheet: "Data", Offset: 1, Posregs: []string{"D2"}}}
+
+	rules, err := NewRules(map[string]string{"PR": "^[A-Z]"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSetCommand("./testdata/test.xlsx", cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Rules = rules
+
+	_, err = s.Execute()
+	e, ok := err.(*RuleError)
+	if !ok {
+		t.Fatalf("Bad error. Got %v, want a *RuleError", err)
+	}
+
+	// pr2-pr5 break the rule too, but they are already set
+	want := []Violation{{"[Data]E2", Posreg, 1, "pr1", `does not match the PR rule "^[A-Z]"`}}
+	if !reflect.DeepEqual(e.Violations, want) {
+		t.Errorf("Bad violations. Got %v, want %v", e.Violations, want)
+	}
+
+	orig, err := ioutil.ReadFile(filepath.Join("testdata", "posreg.va"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "posreg.va"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(orig) {
+		t.Error("posreg.va should not have changed")
+	}
+
+	s, err = NewSetCommand("./testdata/test.xlsx", cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Rules = rules
+	s.Force = true
+
+	result, err := s.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Counts[dir][Posreg]; got != 1 {
+		t.Errorf("Result.Counts[PR]: Got %d, want 1", got)
+	}
+}
@@ -2,18 +2,32 @@ package fexcel
 
 import (
 	"fmt"
+	"io"
+	"sort"
+	"strconv"
 	"sync"
 
-	fanuc "github.com/onerobotics/go-fanuc"
+	"github.com/olekukonko/tablewriter"
 )
 
 type SetCommand struct {
-	fpath   string
-	file    *File
-	targets []*Target
+	fpath     string
+	file      *File
+	targets   []*Target
+	tolerance Tolerance
 
 	Definitions map[Type][]Definition
 	Errors      map[string]*errorList
+
+	warnings []string
+	mux      sync.Mutex // guards warnings while targets are planned
+
+	SnapshotDir  string // where to save a snapshot before making changes
+	SnapshotPath string // the snapshot saved by Execute, if any
+
+	Rules  *Rules // that comments must follow to be set
+	Force  bool   // set comments that break the rules anyway
+	Values bool   // set R and SR values and PR positions as well as comments
 }
 
 func NewSetCommand(fpath string, cfg Config, targets ...string) (*SetCommand, error) {
@@ -21,12 +35,20 @@ func NewSetCommand(fpath string, cfg Config, targets ...string) (*SetCommand, er
 		return nil, fmt.Errorf("Need at least one target")
 	}
 
-	err := cfg.FileConfig.Validate()
+	// a manifest has no cell locations
+	if !isManifest(fpath) {
+		err := cfg.FileConfig.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := cfg.Tolerance.Validate()
 	if err != nil {
 		return nil, err
 	}
 
-	s := SetCommand{fpath: fpath}
+	s := SetCommand{fpath: fpath, tolerance: cfg.Tolerance}
 
 	for _, path := range targets {
 		t, err := NewTarget(path, cfg.Timeout)
@@ -34,10 +56,6 @@ func NewSetCommand(fpath string, cfg Config, targets ...string) (*SetCommand, er
 			return nil, err
 		}
 
-		if _, ok := t.client.(*fanuc.HTTPClient); !ok {
-			return nil, fmt.Errorf("%q is not a valid remote host", path)
-		}
-
 		s.targets = append(s.targets, t)
 	}
 
@@ -90,47 +108,300 @@ func (s *SetCommand) Hosts() []string {
 	return hosts
 }
 
-func (s *SetCommand) Set(wg *sync.WaitGroup, target *Target, result *setResult) {
-	defer wg.Done()
+// A Change is a comment (or value) that will be written to a target.
+type Change struct {
+	Type  Type   `json:"type"`
+	Id    int    `json:"id"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+	Value bool   `json:"value,omitempty"`
+}
+
+// apply makes the change to target
+func (c Change) apply(target *Target) error {
+	if c.Value {
+		return target.SetValue(c.Type, c.Id, c.New)
+	}
+	return target.SetComment(c.Type, c.Id, c.New)
+}
+
+func (c Change) String() string {
+	if c.Value {
+		return fmt.Sprintf("%s[%d] value", c.Type, c.Id)
+	}
+	return fmt.Sprintf("%s[%d]", c.Type, c.Id)
+}
+
+// label names the type and whether a value is changed, e.g. "R value"
+func (c Change) label() string {
+	if c.Value {
+		return c.Type.String() + " value"
+	}
+	return c.Type.String()
+}
+
+// A Plan lists the changes required to bring a target's comments in
+// line with the spreadsheet.
+type Plan struct {
+	Host    string   `json:"host"`
+	Changes []Change `json:"changes"`
+}
+
+func (p *Plan) FprintTable(w io.Writer) {
+	fmt.Fprintf(w, "%s: %d %s\n", p.Host, len(p.Changes), Pluralize("change", len(p.Changes)))
+	if len(p.Changes) == 0 {
+		return
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"Type", "Id", "Old", "New"})
+
+	for _, c := range p.Changes {
+		table.Append([]string{c.label(), strconv.Itoa(c.Id), c.Old, c.New})
+	}
+
+	table.Render()
+}
+
+// plan compares the target's current comments (and values) to the
+// spreadsheet definitions without changing anything.
+func (s *SetCommand) plan(target *Target) ([]Change, error) {
+	if s.Values && s.file.Config.ValueOffset == 0 {
+		return nil, fmt.Errorf("cannot set values without a value column (see --value-offset)")
+	}
 
-	for typ, defs := range s.Definitions {
+	var types []Type
+	for typ := range s.Definitions {
+		types = append(types, typ)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	changes := []Change{}
+	for _, typ := range types {
 		err := target.GetComments(typ)
 		if err != nil {
-			s.Errors[target.Name].Add(err)
-			return
+			return nil, err
 		}
 
-		for _, def := range defs {
+		for _, def := range s.Definitions[typ] {
 			want := Truncated(def.Comment, typ)
-			if got, ok := target.Comments[typ][def.Id]; ok && got == want {
+			got, ok := target.Comments[typ][def.Id]
+			if ok && got == want {
+				continue
+			}
+
+			changes = append(changes, Change{Type: typ, Id: def.Id, Old: got, New: want})
+		}
+
+		if !s.Values || !HasValues(typ) {
+			continue
+		}
+
+		// e.g. PR positions on a remote host. Leaving them out keeps
+		// them from failing the rest of the host's changes.
+		if !target.CanSetValues(typ) {
+			for _, def := range s.Definitions[typ] {
+				if def.Value != "" {
+					s.warn("%s: not setting %s values, they can only be set in backup directories", target.Name, typ)
+					break
+				}
+			}
+			continue
+		}
+
+		err = target.GetValues(typ)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, def := range s.Definitions[typ] {
+			if def.Value == "" {
+				continue
+			}
+
+			// we don't know what we would be overwriting
+			if err, ok := target.ValueErrors[typ][def.Id]; ok {
+				s.warn("%s: not setting %s[%d] value: %s", target.Name, typ, def.Id, err)
+				continue
+			}
+
+			// positions within the tolerance are left alone
+			got, ok := target.Values[typ][def.Id]
+			if ok && valuesMatch(typ, def.Value, got, s.tolerance) {
 				continue
 			}
 
-			err := target.SetComment(typ, def.Id, want)
+			changes = append(changes, Change{Type: typ, Id: def.Id, Old: got, New: def.Value, Value: true})
+		}
+	}
+
+	return changes, nil
+}
+
+func (s *SetCommand) warn(format string, args ...interface{}) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.warnings = append(s.warnings, fmt.Sprintf(format, args...))
+}
+
+// Warnings returns the changes that were left out of the plans, e.g.
+// values that could not be read from a target.
+func (s *SetCommand) Warnings() []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.warnings
+}
+
+func (s *SetCommand) Set(wg *sync.WaitGroup, target *Target, changes []Change, result *setResult) {
+	defer wg.Done()
+
+	// backup directories are only written once we are done
+	defer func() {
+		err := target.Save()
+		if err != nil {
+			s.Errors[target.Name].Add(err)
+		}
+	}()
+
+	for _, c := range changes {
+		err := c.apply(target)
+		if err != nil {
+			s.Errors[target.Name].Add(err)
+			return
+		} else {
+			result.Inc(target.Name, c.Type)
+		}
+	}
+}
+
+// Plan returns the changes Execute would make to each target (in
+// order) without making them. Targets that cannot be planned, e.g.
+// because they are offline, are left out and reported in the error.
+func (s *SetCommand) Plan() ([]*Plan, error) {
+	var plans []*Plan
+	for _, p := range s.planAll() {
+		if p != nil {
+			plans = append(plans, p)
+		}
+	}
+
+	return plans, s.Err()
+}
+
+// planAll plans every target concurrently. The plans of targets that
+// fail are nil; their errors are added to s.Errors.
+func (s *SetCommand) planAll() []*Plan {
+	plans := make([]*Plan, len(s.targets))
+
+	var wg sync.WaitGroup
+	for i, target := range s.targets {
+		wg.Add(1)
+		go func(i int, target *Target) {
+			defer wg.Done()
+
+			changes, err := s.plan(target)
 			if err != nil {
 				s.Errors[target.Name].Add(err)
 				return
-			} else {
-				result.Inc(target.Name, typ)
 			}
-		}
+			plans[i] = &Plan{Host: target.Name, Changes: changes}
+		}(i, target)
 	}
+	wg.Wait()
+
+	return plans
 }
 
+// Execute plans the changes for every target, saves a snapshot of the
+// comments about to be overwritten (if SnapshotDir is set) and then
+// makes the changes. Each target is independent: one that cannot be
+// planned is reported in the error while the others are still changed.
+// Naming rule violations stop every target.
 func (s *SetCommand) Execute() (*setResult, error) {
 	result := newSetResult(s.targets)
 
+	all := s.planAll()
+
+	var plans []*Plan
+	for _, p := range all {
+		if p != nil {
+			plans = append(plans, p)
+		}
+	}
+
+	if s.Rules != nil && !s.Force {
+		err := s.checkRules(plans)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if s.SnapshotDir != "" {
+		snapshot := NewSnapshot(s.fpath, plans)
+		if snapshot.Len() > 0 {
+			var err error
+			s.SnapshotPath, err = snapshot.Save(s.SnapshotDir)
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+
 	var wg sync.WaitGroup
-	for _, target := range s.targets {
+	for i, target := range s.targets {
+		if all[i] == nil {
+			continue
+		}
+
 		wg.Add(1)
-		go s.Set(&wg, target, result)
+		go s.Set(&wg, target, all[i].Changes, result)
 	}
 	wg.Wait()
 
 	return result, s.Err()
 }
 
-func (s SetCommand) Error() string {
+// checkRules returns a RuleError if any of the planned changes would
+// set a comment that breaks the rules.
+func (s *SetCommand) checkRules(plans []*Plan) error {
+	violations, err := s.file.CheckRules(s.Rules)
+	if err != nil {
+		return err
+	}
+
+	type key struct {
+		Type Type
+		Id   int
+	}
+	planned := make(map[key]bool)
+	for _, p := range plans {
+		for _, c := range p.Changes {
+			// the rules only cover comments
+			if !c.Value {
+				planned[key{c.Type, c.Id}] = true
+			}
+		}
+	}
+
+	var e RuleError
+	for _, v := range violations {
+		if planned[key{v.Type, v.Id}] {
+			e.Violations = append(e.Violations, v)
+		}
+	}
+
+	if len(e.Violations) > 0 {
+		return &e
+	}
+
+	return nil
+}
+
+func (s *SetCommand) Error() string {
 	var str string
 	for host, err := range s.Errors {
 		if err.Err() != nil {
@@ -140,7 +411,7 @@ func (s SetCommand) Error() string {
 	return str
 }
 
-func (s SetCommand) Err() error {
+func (s *SetCommand) Err() error {
 	if s.Error() == "" {
 		return nil
 	}
@@ -1,8 +1,12 @@
 package fexcel
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"testing"
 )
@@ -16,8 +20,7 @@ func TestNewSetCommandErrors(t *testing.T) {
 	}{
 		{"./testdata/test.xlsx", Config{}, []string{}, "Need at least one target"},
 		{"./testdata/test.xlsx", Config{}, []string{"foo"}, "no cell locations defined"},
-		{"./testdata/test.xlsx", Config{FileConfig: FileConfig{Numregs: "A2"}}, []string{"./testdata"}, "offset must be nonzero"},
-		{"./testdata/test.xlsx", Config{FileConfig: FileConfig{Numregs: "A2", Offset: 1}}, []string{"./testdata"}, "\"./testdata\" is not a valid remote host"},
+		{"./testdata/test.xlsx", Config{FileConfig: FileConfig{Numregs: []string{"A2"}}}, []string{"./testdata"}, "offset must be nonzero"},
 	}
 
 	for id, test := range tests {
@@ -33,7 +36,7 @@ func TestNewSetCommandErrors(t *testing.T) {
 	}
 
 	// valid
-	_, err := NewSetCommand("./testdata/test.xlsx", Config{FileConfig: FileConfig{Numregs: "Data:A2", Offset: 1}}, "127.0.0.1")
+	_, err := NewSetCommand("./testdata/test.xlsx", Config{FileConfig: FileConfig{Numregs: []string{"Data:A2"}, Offset: 1}}, "127.0.0.1")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -61,7 +64,7 @@ func TestSetCommand(t *testing.T) {
 
 	hosts := []string{s1.URL, s2.URL}
 
-	s, err := NewSetCommand("./testdata/test.xlsx", Config{FileConfig: FileConfig{Numregs: "Data:A2", Offset: 1}}, hosts...)
+	s, err := NewSetCommand("./testdata/test.xlsx", Config{FileConfig: FileConfig{Numregs: []string{"Data:A2"}, Offset: 1}}, hosts...)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -82,3 +85,202 @@ func TestSetCommand(t *testing.T) {
 		t.Errorf("comment request called %d times. Want 8", commentCount)
 	}
 }
+
+// copies the backup files in testdata to a temporary directory
+func tempBackup(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "fexcel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filename := range []string{"numreg.va", "posreg.va", "strreg.va", "sysvars.va", "iostate.dg"} {
+		b, err := ioutil.ReadFile(filepath.Join("testdata", filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ioutil.WriteFile(filepath.Join(dir, filename), b, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestSetCommandBackup(t *testing.T) {
+	dir := tempBackup(t)
+	defer os.RemoveAll(dir)
+
+	cfg := Config{FileConfig: FileConfig{
+		Sheet:   "Data",
+		Offset:  1,
+		Numregs: []string{"A2"},
+		Posregs: []string{"D2"},
+		Sregs:   []string{"G2"},
+		Flags:   []string{"J2"},
+		Rins:    []string{"IO:E2"},
+		Ualms:   []string{"Alarms:A2"},
+	}}
+
+	s, err := NewSetCommand("./testdata/test.xlsx", cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[Type]int{Numreg: 0, Posreg: 1, Sreg: 0, Flag: 1, Rin: 1, Ualm: 1}
+	for typ, want := range counts {
+		if got := result.Counts[dir][typ]; got != want {
+			t.Errorf("Result.Counts[%s]: Got %d, want %d", typ, got, want)
+		}
+	}
+
+	// only the comments should have changed
+	changes := []struct {
+		filename string
+		old      string
+		new      string
+	}{
+		{"numreg.va", "", ""},
+		{"strreg.va", "", ""},
+		{"posreg.va", "[1,1] =   'Maintenance'", "[1,1] =   'pr1'"},
+		{"iostate.dg", "FLG[   1] OFF  asdf                      FLG", "FLG[   1] OFF  f1                        FLG"},
+		{"iostate.dg", "RI[   2] OFF  \n", "RI[   2] OFF  rin2\n"},
+		{"sysvars.va", "[4] = 'test 4'", "[4] = 'test four'"},
+	}
+
+	for _, c := range changes {
+		orig, err := ioutil.ReadFile(filepath.Join("testdata", c.filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(filepath.Join(dir, c.filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// apply every expected change for the file
+		want := string(orig)
+		for _, other := range changes {
+			if other.filename == c.filename && other.old != "" {
+				want = strings.Replace(want, other.old, other.new, 1)
+			}
+		}
+
+		if string(got) != want {
+			t.Errorf("%s was not rewritten as expected", c.filename)
+		}
+	}
+
+	// nothing left to do the second time around
+	s, err = NewSetCommand("./testdata/test.xlsx", cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = s.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for typ := range counts {
+		if got := result.Counts[dir][typ]; got != 0 {
+			t.Errorf("Result.Counts[%s]: Got %d, want 0", typ, got)
+		}
+	}
+}
+
+func TestSetCommandOfflineHost(t *testing.T) {
+	dir := tempBackup(t)
+	defer os.RemoveAll(dir)
+
+	offline := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		http.Error(rw, "Service unavailable", http.StatusServiceUnavailable)
+	}))
+	defer offline.Close()
+
+	cfg := Config{FileConfig: FileConfig{Sheet: "Data", Offset: 1, Posregs: []string{"D2"}}}
+	s, err := NewSetCommand("./testdata/test.xlsx", cfg, offline.URL, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SnapshotDir = filepath.Join(dir, "snapshots")
+
+	plans, err := s.Plan()
+	if err == nil {
+		t.Error("Expected an error planning the offline host")
+	}
+	if len(plans) != 1 || plans[0].Host != dir {
+		t.Errorf("Expected a single plan for %s. Got %v", dir, plans)
+	}
+
+	s, err = NewSetCommand("./testdata/test.xlsx", cfg, offline.URL, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SnapshotDir = filepath.Join(dir, "snapshots")
+
+	// the backup is still changed
+	result, err := s.Execute()
+	if err == nil || !strings.Contains(err.Error(), offline.URL) {
+		t.Errorf("Expected an error for %s. Got %v", offline.URL, err)
+	}
+	if got := result.Counts[dir][Posreg]; got != 1 {
+		t.Errorf("Result.Counts[%s]: Got %d, want 1", Posreg, got)
+	}
+
+	snapshot, err := ReadSnapshot(s.SnapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := snapshot.PlanFor(offline.URL); err == nil {
+		t.Errorf("Snapshot has a plan for %s", offline.URL)
+	}
+}
+
+func TestSetCommandPlan(t *testing.T) {
+	cfg := Config{FileConfig: FileConfig{
+		Sheet:   "Data",
+		Offset:  1,
+		Numregs: []string{"A2"},
+		Posregs: []string{"D2"},
+		Flags:   []string{"J2"},
+		Ualms:   []string{"Alarms:A2"},
+	}}
+
+	s, err := NewSetCommand("./testdata/test.xlsx", cfg, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plans, err := s.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plans) != 1 || plans[0].Host != "testdata" {
+		t.Fatalf("Expected a single plan for testdata. Got %v", plans)
+	}
+
+	want := []Change{
+		{Type: Posreg, Id: 1, Old: "Maintenance", New: "pr1"},
+		{Type: Ualm, Id: 4, Old: "test 4", New: "test four"},
+		{Type: Flag, Id: 1, Old: "asdf", New: "f1"},
+	}
+
+	changes := plans[0].Changes
+	if len(changes) != len(want) {
+		t.Fatalf("Got %d changes, want %d", len(changes), len(want))
+	}
+
+	for i, c := range changes {
+		if c != want[i] {
+			t.Errorf("Bad change. Got %v, want %v", c, want[i])
+		}
+	}
+}
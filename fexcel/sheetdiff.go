@@ -0,0 +1,154 @@
+package fexcel
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// A SheetDiffCommand compares two revisions of a spreadsheet.
+type SheetDiffCommand struct {
+	before *File
+	after  *File
+}
+
+func NewSheetDiffCommand(oldPath string, oldCfg FileConfig, newPath string, newCfg FileConfig) (*SheetDiffCommand, error) {
+	before, err := OpenFile(oldPath, oldCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := OpenFile(newPath, newCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SheetDiffCommand{before: before, after: after}, nil
+}
+
+// A SheetChangeStatus says how a definition changed between revisions
+type SheetChangeStatus string
+
+const (
+	SheetAdded   SheetChangeStatus = "added"
+	SheetRemoved SheetChangeStatus = "removed"
+	SheetChanged SheetChangeStatus = "changed"
+)
+
+// A SheetChange is a difference between two revisions of a
+// spreadsheet. Constants are identified by name instead of id.
+type SheetChange struct {
+	Type   Type              `json:"type"`
+	Id     string            `json:"id"`
+	Status SheetChangeStatus `json:"status"`
+	Old    string            `json:"old"`
+	New    string            `json:"new"`
+}
+
+// diffMaps reports the differences between two id -> comment maps
+func diffMaps(t Type, before, after map[string]string) (changes []SheetChange) {
+	for id, o := range before {
+		if n, ok := after[id]; !ok {
+			changes = append(changes, SheetChange{t, id, SheetRemoved, o, ""})
+		} else if n != o {
+			changes = append(changes, SheetChange{t, id, SheetChanged, o, n})
+		}
+	}
+
+	for id, n := range after {
+		if _, ok := before[id]; !ok {
+			changes = append(changes, SheetChange{t, id, SheetAdded, "", n})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, errA := strconv.Atoi(changes[i].Id)
+		b, errB := strconv.Atoi(changes[j].Id)
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return changes[i].Id < changes[j].Id
+	})
+
+	return
+}
+
+func (f *File) commentsFor(t Type) (map[string]string, error) {
+	comments := make(map[string]string)
+	if _, defined := f.Locations[t]; !defined {
+		return comments, nil
+	}
+
+	if t == Constant {
+		return f.Constants()
+	}
+
+	defs, err := f.Definitions(t)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range defs {
+		comments[strconv.Itoa(d.Id)] = d.Comment
+	}
+
+	return comments, nil
+}
+
+// Compare returns every added, removed and changed definition and
+// constant, ordered by type and id.
+func (s *SheetDiffCommand) Compare() ([]SheetChange, error) {
+	seen := make(map[Type]bool)
+	for t := range s.before.Locations {
+		seen[t] = true
+	}
+	for t := range s.after.Locations {
+		seen[t] = true
+	}
+
+	var types []Type
+	for t := range seen {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	changes := []SheetChange{}
+	for _, t := range types {
+		before, err := s.before.commentsFor(t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", filepath.Base(s.before.path), err)
+		}
+
+		after, err := s.after.commentsFor(t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", filepath.Base(s.after.path), err)
+		}
+
+		changes = append(changes, diffMaps(t, before, after)...)
+	}
+
+	return changes, nil
+}
+
+func (s *SheetDiffCommand) FprintTable(w io.Writer, changes []SheetChange) {
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"Type", "Id", "Status", filepath.Base(s.before.path), filepath.Base(s.after.path)})
+
+	for _, c := range changes {
+		table.Append([]string{c.Type.String(), c.Id, string(c.Status), c.Old, c.New})
+	}
+
+	table.Render()
+}
+
+func (s *SheetDiffCommand) Warnings() []string {
+	var warnings []string
+	warnings = append(warnings, s.before.Warnings...)
+	return append(warnings, s.after.Warnings...)
+}
@@ -0,0 +1,82 @@
+package fexcel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSheetDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fexcel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2"}, Constants: []string{"M2"}}
+
+	// make a new revision of the test spreadsheet
+	f, err := OpenFile("testdata/test.xlsx", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edits := []struct {
+		col, row int
+		value    interface{}
+	}{
+		{2, 3, "deux"}, // R[2] changed
+		{1, 6, 7},      // R[5] removed, R[7] added
+		{14, 2, "baz"}, // FOO changed
+		{13, 4, "QUX"}, // QUX added
+		{14, 4, "quux"},
+	}
+	for _, e := range edits {
+		err = f.SetValue("Data", e.col, e.row, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	newPath := filepath.Join(dir, "rev2.xlsx")
+	err = f.book.SaveAs(newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new revision has its own config
+	newCfg := cfg
+	newCfg.Posregs = []string{"D2"}
+
+	s, err := NewSheetDiffCommand("testdata/test.xlsx", cfg, newPath, newCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := s.Compare()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SheetChange{
+		{Constant, "FOO", SheetChanged, "bar", "baz"},
+		{Constant, "QUX", SheetAdded, "", "quux"},
+		{Numreg, "2", SheetChanged, "two", "deux"},
+		{Numreg, "5", SheetRemoved, "five", ""},
+		{Numreg, "7", SheetAdded, "", "five"},
+		{Posreg, "1", SheetAdded, "", "pr1"},
+		{Posreg, "2", SheetAdded, "", "pr2"},
+		{Posreg, "3", SheetAdded, "", "pr3"},
+		{Posreg, "4", SheetAdded, "", "pr4"},
+		{Posreg, "5", SheetAdded, "", "pr5"},
+	}
+
+	if len(changes) != len(want) {
+		t.Fatalf("Got %d changes, want %d: %v", len(changes), len(want), changes)
+	}
+
+	for i, c := range changes {
+		if c != want[i] {
+			t.Errorf("Bad change. Got %v, want %v", c, want[i])
+		}
+	}
+}
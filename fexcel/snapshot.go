@@ -0,0 +1,80 @@
+package fexcel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A Snapshot records the comments a SetCommand is about to overwrite
+// so they can be restored later with a RollbackCommand.
+type Snapshot struct {
+	Time  time.Time `json:"time"`
+	File  string    `json:"file"`
+	Plans []*Plan   `json:"plans"`
+}
+
+const snapshotTimeFormat = "20060102-150405.000"
+
+func NewSnapshot(fpath string, plans []*Plan) *Snapshot {
+	return &Snapshot{Time: time.Now(), File: fpath, Plans: plans}
+}
+
+func ReadSnapshot(path string) (*Snapshot, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Snapshot
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot %q: %s", path, err)
+	}
+
+	return &s, nil
+}
+
+// Len returns the total number of changes in the snapshot.
+func (s *Snapshot) Len() (i int) {
+	for _, p := range s.Plans {
+		i += len(p.Changes)
+	}
+
+	return i
+}
+
+// PlanFor returns the snapshot's plan for the provided host.
+func (s *Snapshot) PlanFor(host string) (*Plan, error) {
+	for _, p := range s.Plans {
+		if p.Host == host {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("snapshot does not contain %q", host)
+}
+
+// Save writes the snapshot to a timestamped file in dir and returns
+// the path of the new file.
+func (s *Snapshot) Save(dir string) (string, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "snapshot-"+s.Time.Format(snapshotTimeFormat)+".json")
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("snapshot %q already exists", path)
+	}
+
+	return path, ioutil.WriteFile(path, b, 0644)
+}
@@ -21,6 +21,24 @@ func MaxLengthFor(t Type) int {
 	}
 }
 
+// MaxId returns the highest id of t that a controller can be
+// configured with. Controllers usually have far fewer, so this only
+// catches ids that can't be right anywhere.
+func MaxId(t Type) int {
+	switch t {
+	case Numreg, Posreg:
+		return 3000
+	case Sreg, Ualm:
+		return 1000
+	case Rin, Rout:
+		return 24
+	case Flag:
+		return 4096
+	default:
+		return 9999
+	}
+}
+
 func Truncated(s string, t Type) string {
 	if max := MaxLengthFor(t); len(s) > max {
 		return s[:max]
@@ -0,0 +1,257 @@
+package fexcel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// A Baseline is the state of a target's comments after the last sync.
+type Baseline struct {
+	Time     time.Time               `json:"time"`
+	Target   string                  `json:"target"`
+	Comments map[Type]map[int]string `json:"comments"`
+}
+
+func ReadBaseline(path string) (*Baseline, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseline Baseline
+	err = json.Unmarshal(b, &baseline)
+	if err != nil {
+		return nil, fmt.Errorf("invalid baseline %q: %s", path, err)
+	}
+
+	return &baseline, nil
+}
+
+func (b *Baseline) Save(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+var unsafeFilenameRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// DefaultBaselinePath returns the baseline path for a spreadsheet and
+// target, e.g. ./io.192.168.1.1.sync.json for ./io.xlsx
+func DefaultBaselinePath(fpath string, target string) string {
+	name := strings.TrimSuffix(filepath.Base(fpath), filepath.Ext(fpath))
+	name += "." + strings.Trim(unsafeFilenameRegexp.ReplaceAllString(target, "_"), "_") + ".sync.json"
+	return filepath.Join(filepath.Dir(fpath), name)
+}
+
+// A Conflict is a comment that was changed in both the spreadsheet and
+// on the target since the last sync.
+type Conflict struct {
+	Type   Type   `json:"type"`
+	Id     int    `json:"id"`
+	Base   string `json:"base"`
+	Sheet  string `json:"sheet"`
+	Target string `json:"target"`
+}
+
+type SyncPlan struct {
+	ToTarget  []Change   `json:"toTarget"`
+	ToSheet   []Change   `json:"toSheet"`
+	Conflicts []Conflict `json:"conflicts"`
+
+	cells    map[Type]map[int]cell // spreadsheet cell of each id
+	baseline *Baseline             // after a successful sync
+}
+
+// A Syncer merges spreadsheet and target comments changed since the
+// last sync in both directions.
+type Syncer struct {
+	file         *File
+	target       *Target
+	baselinePath string
+	baseline     *Baseline
+}
+
+func NewSyncer(path string, cfg Config, targetPath string, baselinePath string) (*Syncer, error) {
+	f, err := OpenFile(path, cfg.FileConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := NewTarget(targetPath, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if baselinePath == "" {
+		baselinePath = DefaultBaselinePath(path, targetPath)
+	}
+
+	s := Syncer{file: f, target: t, baselinePath: baselinePath}
+
+	s.baseline, err = ReadBaseline(baselinePath)
+	if os.IsNotExist(err) {
+		// first sync
+		s.baseline = &Baseline{Comments: make(map[Type]map[int]string)}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (s *Syncer) BaselinePath() string {
+	return s.baselinePath
+}
+
+// Plan compares the spreadsheet and target to the baseline without
+// changing anything. Ids that are not in the spreadsheet are ignored.
+func (s *Syncer) Plan() (*SyncPlan, error) {
+	p := SyncPlan{
+		ToTarget:  []Change{},
+		ToSheet:   []Change{},
+		Conflicts: []Conflict{},
+		cells:     make(map[Type]map[int]cell),
+		baseline:  &Baseline{Target: s.target.Name, Comments: make(map[Type]map[int]string)},
+	}
+
+	var types []Type
+	for t := range s.file.Locations {
+		if t != Constant {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	for _, t := range types {
+		err := s.target.GetComments(t)
+		if err != nil {
+			return nil, err
+		}
+
+		defs, cells, err := s.file.definitions(t)
+		if err != nil {
+			return nil, err
+		}
+
+		p.cells[t] = make(map[int]cell)
+		p.baseline.Comments[t] = make(map[int]string)
+
+		for i, def := range defs {
+			p.cells[t][def.Id] = cells[i]
+
+			sheet := Truncated(def.Comment, t)
+			target, defined := s.target.Comments[t][def.Id]
+			if !defined {
+				return nil, fmt.Errorf("%s[%d] is not defined on %s", t, def.Id, s.target.Name)
+			}
+			base, synced := s.baseline.Comments[t][def.Id]
+
+			sheetChanged := !synced || sheet != base
+			targetChanged := !synced || target != base
+
+			switch {
+			case sheet == target:
+				p.baseline.Comments[t][def.Id] = sheet
+			// an id range shares a single comment cell, so its
+			// comments can't be updated one at a time
+			case sheetChanged && targetChanged, targetChanged && cells[i].ranged:
+				p.Conflicts = append(p.Conflicts, Conflict{t, def.Id, base, def.Comment, target})
+				if synced {
+					p.baseline.Comments[t][def.Id] = base
+				}
+			case sheetChanged:
+				p.ToTarget = append(p.ToTarget, Change{Type: t, Id: def.Id, Old: target, New: sheet})
+				p.baseline.Comments[t][def.Id] = sheet
+			case targetChanged:
+				p.ToSheet = append(p.ToSheet, Change{Type: t, Id: def.Id, Old: def.Comment, New: target})
+				p.baseline.Comments[t][def.Id] = target
+			}
+		}
+	}
+
+	return &p, nil
+}
+
+// Sync applies the non-conflicting changes and records the new
+// baseline.
+func (s *Syncer) Sync() (*SyncPlan, error) {
+	p, err := s.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range p.ToTarget {
+		err = s.target.SetComment(c.Type, c.Id, c.New)
+		if err != nil {
+			// keep the changes that were made, but say if we couldn't
+			if saveErr := s.target.Save(); saveErr != nil {
+				return nil, fmt.Errorf("%s (and saving the changes already made failed: %s)", err, saveErr)
+			}
+			return nil, err
+		}
+	}
+	err = s.target.Save()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.ToSheet) > 0 {
+		for _, c := range p.ToSheet {
+			err = s.file.setComment(p.cells[c.Type][c.Id], c.New)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = s.file.Save()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.baseline.Time = time.Now()
+	err = p.baseline.Save(s.baselinePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *SyncPlan) FprintTable(w io.Writer) {
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"Type", "Id", "Action", "Baseline", "Spreadsheet", "Target"})
+
+	for _, c := range p.ToTarget {
+		table.Append([]string{c.Type.String(), strconv.Itoa(c.Id), "-> target", c.Old, c.New, c.Old})
+	}
+	for _, c := range p.ToSheet {
+		table.Append([]string{c.Type.String(), strconv.Itoa(c.Id), "-> spreadsheet", Truncated(c.Old, c.Type), c.Old, c.New})
+	}
+	for _, c := range p.Conflicts {
+		table.Append([]string{c.Type.String(), strconv.Itoa(c.Id), "CONFLICT", c.Base, c.Sheet, c.Target})
+	}
+
+	table.Render()
+}
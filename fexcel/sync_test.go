@@ -0,0 +1,122 @@
+package fexcel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultBaselinePath(t *testing.T) {
+	tests := []struct {
+		fpath  string
+		target string
+		want   string
+	}{
+		{"io.xlsx", "192.168.1.1", "io.192.168.1.1.sync.json"},
+		{"doc/io.xlsx", "http://127.0.0.1:8080", filepath.Join("doc", "io.http_127.0.0.1_8080.sync.json")},
+		{"io.xlsx", "./backup/", "io.._backup.sync.json"},
+	}
+
+	for _, test := range tests {
+		if got := DefaultBaselinePath(test.fpath, test.target); got != test.want {
+			t.Errorf("DefaultBaselinePath(%q, %q): Got %q, want %q", test.fpath, test.target, got, test.want)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	dir, fpath := tempSpreadsheet(t)
+	defer os.RemoveAll(dir)
+	backup := tempBackup(t)
+	defer os.RemoveAll(backup)
+
+	cfg := Config{FileConfig: FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A2"}, Posregs: []string{"D2"}}}
+	baselinePath := filepath.Join(dir, "baseline.json")
+
+	sync := func() *SyncPlan {
+		s, err := NewSyncer(fpath, cfg, backup, baselinePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := s.Sync()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	// without a baseline, any difference is a conflict
+	p := sync()
+	if len(p.ToTarget) != 0 || len(p.ToSheet) != 0 || len(p.Conflicts) != 1 {
+		t.Fatalf("Bad first sync: %v", p)
+	}
+	if c := p.Conflicts[0]; c.Type != Posreg || c.Id != 1 || c.Sheet != "pr1" || c.Target != "Maintenance" {
+		t.Errorf("Bad conflict: %v", c)
+	}
+
+	// resolve the conflict and make some changes on both sides
+	target, err := NewTarget(backup, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []Change{{Type: Posreg, Id: 1, New: "pr1"}, {Type: Numreg, Id: 3, New: "trois"}, {Type: Numreg, Id: 4, New: "vier"}, {Type: Numreg, Id: 5, New: "cinq"}} {
+		err = target.SetComment(c.Type, c.Id, c.New)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = target.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenFile(fpath, cfg.FileConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row, comment := range map[int]string{3: "deux", 5: "quatre", 6: "cinq"} {
+		err = f.SetValue("Data", 2, row, comment)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = f.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p = sync()
+	if len(p.ToTarget) != 1 || p.ToTarget[0] != (Change{Type: Numreg, Id: 2, Old: "two", New: "deux"}) {
+		t.Errorf("Bad changes to target: %v", p.ToTarget)
+	}
+	if len(p.ToSheet) != 1 || p.ToSheet[0] != (Change{Type: Numreg, Id: 3, Old: "three", New: "trois"}) {
+		t.Errorf("Bad changes to spreadsheet: %v", p.ToSheet)
+	}
+	if len(p.Conflicts) != 1 || p.Conflicts[0] != (Conflict{Numreg, 4, "four", "quatre", "vier"}) {
+		t.Errorf("Bad conflicts: %v", p.Conflicts)
+	}
+
+	// both sides have the merged changes
+	d, err := NewDiffCommand(fpath, cfg, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := d.CompareAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		for _, c := range r.Comparisons {
+			equal := c.Equal() || Truncated(c.Want, r.Type) == c.Got[0]
+			if equal == (r.Type == Numreg && c.Id == 4) {
+				t.Errorf("%s[%d]: Got %q, want %q", r.Type, c.Id, c.Got[0], c.Want)
+			}
+		}
+	}
+
+	// only the conflict remains
+	p = sync()
+	if len(p.ToTarget) != 0 || len(p.ToSheet) != 0 || len(p.Conflicts) != 1 {
+		t.Errorf("Bad final sync: %v", p)
+	}
+}
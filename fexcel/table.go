@@ -0,0 +1,197 @@
+package fexcel
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+// excelize can add tables, but it can't list them, so we read the
+// table parts out of the workbook ourselves.
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		Id     string `xml:"Id,attr"`
+		Type   string `xml:"Type,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorkbookSheets struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		Id   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxTableColumn struct {
+	Name string `xml:"name,attr"`
+}
+
+type xlsxTable struct {
+	Name           string            `xml:"name,attr"`
+	DisplayName    string            `xml:"displayName,attr"`
+	Ref            string            `xml:"ref,attr"`
+	HeaderRowCount *int              `xml:"headerRowCount,attr"`
+	TotalsRowCount int               `xml:"totalsRowCount,attr"`
+	Columns        []xlsxTableColumn `xml:"tableColumns>tableColumn"`
+}
+
+// A table is an Excel table (ListObject) and the sheet it is on
+type table struct {
+	xlsxTable
+	Sheet string
+}
+
+func (f *File) readXML(name string, v interface{}) error {
+	xlsx, ok := f.excel()
+	if !ok {
+		return fmt.Errorf("tables are only supported in .xlsx files, not %s", f.path)
+	}
+
+	b, ok := xlsx.XLSX[name]
+	if !ok {
+		return fmt.Errorf("%s not found in %s", name, f.path)
+	}
+
+	return xml.Unmarshal(b, v)
+}
+
+// resolves a relationship target relative to the part that owns it
+func relTarget(owner, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return target[1:]
+	}
+	return path.Join(path.Dir(owner), target)
+}
+
+// relsPath returns the relationships part for the provided part e.g.
+// xl/worksheets/_rels/sheet1.xml.rels for xl/worksheets/sheet1.xml
+func relsPath(part string) string {
+	return path.Join(path.Dir(part), "_rels", path.Base(part)+".rels")
+}
+
+// tables returns every table in the workbook
+func (f *File) tables() ([]table, error) {
+	const workbook = "xl/workbook.xml"
+
+	var wb xlsxWorkbookSheets
+	err := f.readXML(workbook, &wb)
+	if err != nil {
+		return nil, err
+	}
+	xlsx, _ := f.excel()
+
+	var wbRels xlsxRelationships
+	err = f.readXML(relsPath(workbook), &wbRels)
+	if err != nil {
+		return nil, err
+	}
+
+	sheetParts := make(map[string]string)
+	for _, r := range wbRels.Relationships {
+		sheetParts[r.Id] = relTarget(workbook, r.Target)
+	}
+
+	var tables []table
+	for _, sheet := range wb.Sheets {
+		part := sheetParts[sheet.Id]
+
+		// sheets without relationships have no tables
+		if _, ok := xlsx.XLSX[relsPath(part)]; !ok {
+			continue
+		}
+
+		var rels xlsxRelationships
+		err = f.readXML(relsPath(part), &rels)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range rels.Relationships {
+			if !strings.HasSuffix(r.Type, "/table") {
+				continue
+			}
+
+			var t xlsxTable
+			err = f.readXML(relTarget(part, r.Target), &t)
+			if err != nil {
+				return nil, err
+			}
+
+			tables = append(tables, table{xlsxTable: t, Sheet: sheet.Name})
+		}
+	}
+
+	return tables, nil
+}
+
+// resolveTable starts the location at the first data row of the id
+// column and reads every row of the table's declared range.
+func (f *File) resolveTable(loc *Location) error {
+	tables, err := f.tables()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tables {
+		if !strings.EqualFold(t.Name, loc.Table) && !strings.EqualFold(t.DisplayName, loc.Table) {
+			continue
+		}
+
+		cells := strings.Split(t.Ref, ":")
+		if len(cells) != 2 {
+			return fmt.Errorf("table %q has an invalid range: %q", loc.Table, t.Ref)
+		}
+
+		col, first, err := excelize.CellNameToCoordinates(cells[0])
+		if err != nil {
+			return err
+		}
+		_, last, err := excelize.CellNameToCoordinates(cells[1])
+		if err != nil {
+			return err
+		}
+
+		headerRows := 1
+		if t.HeaderRowCount != nil {
+			headerRows = *t.HeaderRowCount
+		}
+		first += headerRows
+		last -= t.TotalsRowCount
+
+		idCol, commentCol := -1, -1
+		for i, c := range t.Columns {
+			if idCol < 0 && headerEqual(c.Name, loc.Header) {
+				idCol = i
+			}
+			if commentCol < 0 && headerEqual(c.Name, loc.CommentHeader) {
+				commentCol = i
+			}
+		}
+		if idCol < 0 {
+			return fmt.Errorf("column %q not found in table %q", loc.Header, loc.Table)
+		}
+		if commentCol < 0 {
+			return fmt.Errorf("column %q not found in table %q", loc.CommentHeader, loc.Table)
+		}
+		if idCol == commentCol {
+			return fmt.Errorf("id and comment columns of table %q must be different", loc.Table)
+		}
+
+		loc.Sheet = t.Sheet
+		loc.Axis, err = excelize.CoordinatesToCellName(col+idCol, first)
+		if err != nil {
+			return err
+		}
+		loc.Offset = commentCol - idCol
+		loc.End = last
+
+		return nil
+	}
+
+	return fmt.Errorf("table %q not found", loc.Table)
+}
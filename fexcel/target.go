@@ -1,7 +1,15 @@
 package fexcel
 
 import (
-	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	fanuc "github.com/onerobotics/go-fanuc"
@@ -24,10 +32,14 @@ var fanucType = [...]fanuc.Type{
 }
 
 type Target struct {
-	client fanuc.Client
+	client  fanuc.Client
+	timeout time.Duration
+	edits   map[string]string // pending backup file changes by filename
 
-	Name     string
-	Comments map[Type]map[int]string
+	Name        string
+	Comments    map[Type]map[int]string
+	Values      map[Type]map[int]string // R, SR and PR values (see GetValues)
+	ValueErrors map[Type]map[int]error  // values that could not be read, by id
 }
 
 func NewTarget(path string, timeout int) (*Target, error) {
@@ -41,8 +53,11 @@ func NewTarget(path string, timeout int) (*Target, error) {
 
 	var t Target
 	t.client = client
+	t.timeout = time.Duration(timeout) * time.Second
 	t.Name = path
 	t.Comments = make(map[Type]map[int]string)
+	t.Values = make(map[Type]map[int]string)
+	t.ValueErrors = make(map[Type]map[int]error)
 
 	return &t, nil
 }
@@ -56,8 +71,13 @@ func (t *Target) GetComments(typ Type) error {
 		if err != nil {
 			return err
 		}
+		t.Values[typ] = make(map[int]string)
 		for _, r := range numregs {
 			t.Comments[typ][r.Id] = r.Comment
+			t.Values[typ][r.Id], err = FormatValue(typ, r.Value)
+			if err != nil {
+				return err
+			}
 		}
 	case Posreg:
 		posregs, err := t.client.PositionRegisters()
@@ -73,6 +93,33 @@ func (t *Target) GetComments(typ Type) error {
 			return err
 		}
 		for _, r := range ports {
+			// iostate.dg pads comments when ports are listed in columns
+			t.Comments[typ][r.Id] = strings.TrimRight(r.Comment, " ")
+		}
+	case Sreg:
+		src, err := t.readMD(MDFile(typ))
+		if err != nil {
+			return err
+		}
+		sregs, err := parseStringRegisters(src)
+		if err != nil {
+			return err
+		}
+		t.Values[typ] = make(map[int]string)
+		for _, r := range sregs {
+			t.Comments[typ][r.Id] = r.Comment
+			t.Values[typ][r.Id] = r.Value
+		}
+	case Ualm:
+		src, err := t.readMD(MDFile(typ))
+		if err != nil {
+			return err
+		}
+		ualms, err := parseUserAlarms(src)
+		if err != nil {
+			return err
+		}
+		for _, r := range ualms {
 			t.Comments[typ][r.Id] = r.Comment
 		}
 	}
@@ -80,10 +127,233 @@ func (t *Target) GetComments(typ Type) error {
 	return nil
 }
 
+// GetValues reads the values of typ (see HasValues). R and SR values
+// are read with their comments if they haven't been already. PR
+// positions are parsed from posreg.va, and the ones that cannot be are
+// left out of Values and recorded in ValueErrors instead.
+func (t *Target) GetValues(typ Type) error {
+	if !HasValues(typ) {
+		return fmt.Errorf("%ss do not have values", typ)
+	}
+
+	if typ != Posreg {
+		if _, ok := t.Values[typ]; ok {
+			return nil
+		}
+		return t.GetComments(typ)
+	}
+
+	src, err := t.readMD(MDFile(typ))
+	if err != nil {
+		return err
+	}
+	posregs, err := parsePositionRegisters(src)
+	if err != nil {
+		return err
+	}
+
+	t.Values[typ] = make(map[int]string)
+	t.ValueErrors[typ] = make(map[int]error)
+	for _, r := range posregs {
+		if r.Group != 1 {
+			continue
+		}
+
+		if r.Err != nil {
+			t.ValueErrors[typ][r.Id] = r.Err
+			continue
+		}
+		t.Values[typ][r.Id] = r.Position.String()
+	}
+
+	return nil
+}
+
+// readMD returns the contents of a file on the target's MD: device.
+// Backup directories are read from disk; hosts are read over HTTP.
+func (t *Target) readMD(filename string) (string, error) {
+	switch t.client.(type) {
+	case *fanuc.FileClient:
+		b, err := ioutil.ReadFile(filepath.Join(t.Name, filename))
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	case *fanuc.HTTPClient:
+		return t.get("/MD/" + filename)
+	}
+
+	return "", fmt.Errorf("cannot read %s from %q", filename, t.Name)
+}
+
+// get requests path from a remote host and returns the response body
+func (t *Target) get(path string) (string, error) {
+	baseURL := t.Name
+	if ip := net.ParseIP(baseURL); ip != nil {
+		baseURL = "http://" + baseURL
+	}
+
+	// FANUC likes %20 over + for spaces
+	path = strings.ReplaceAll(path, "+", "%20")
+
+	client := http.Client{Timeout: t.timeout}
+	res, err := client.Get(baseURL + path)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		// ok
+	case http.StatusForbidden:
+		return "", fanuc.ErrForbidden
+	case http.StatusUnauthorized:
+		return "", fanuc.ErrUnauthorized
+	default:
+		return "", fmt.Errorf("Request for %s failed (%d)", path, res.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// SetComment sets the comment for typ[id] on the target. Comments
+// are written to a remote host immediately, but changes to a backup
+// directory are kept in memory until Save is called.
 func (t *Target) SetComment(typ Type, id int, comment string) error {
-	if c, ok := t.client.(*fanuc.HTTPClient); ok {
+	switch c := t.client.(type) {
+	case *fanuc.HTTPClient:
 		return c.SetComment(fanucType[typ], id, comment)
-	} else {
-		return errors.New("need a fanuc.HTTPClient")
+	case *fanuc.FileClient:
+		filename := MDFile(typ)
+		if filename == "" {
+			return fmt.Errorf("cannot set comment for %s", typ)
+		}
+
+		return t.edit(filename, func(src string) (string, error) {
+			return ReplaceComment(typ, src, id, comment)
+		})
+	}
+
+	return fmt.Errorf("cannot set comments on %q", t.Name)
+}
+
+// KAREL comment tool function codes for values
+var valueCodes = map[Type]int{
+	Numreg: 2,
+	Sreg:   15,
+}
+
+// CanSetValues reports whether values of typ can be set on the target.
+// Remote hosts can only have R and SR values set.
+func (t *Target) CanSetValues(typ Type) bool {
+	switch t.client.(type) {
+	case *fanuc.HTTPClient:
+		_, ok := valueCodes[typ]
+		return ok
+	case *fanuc.FileClient:
+		return HasValues(typ)
+	}
+
+	return false
+}
+
+// SetValue sets the value of typ[id] on the target like SetComment.
+// value must already be formatted by FormatValue. PR positions can
+// only be set in backup directories.
+func (t *Target) SetValue(typ Type, id int, value string) error {
+	if !HasValues(typ) {
+		return fmt.Errorf("cannot set value for %s", typ)
+	}
+
+	switch t.client.(type) {
+	case *fanuc.HTTPClient:
+		code, ok := valueCodes[typ]
+		if !ok {
+			return fmt.Errorf("cannot set %s values on %q, only in backup directories", typ, t.Name)
+		}
+
+		params := url.Values{}
+		params.Set("sFc", strconv.Itoa(code))
+		params.Set("sIndx", strconv.Itoa(id))
+		params.Set("sValue", value)
+		if typ == Numreg {
+			// integers and reals are stored differently
+			real := "-1"
+			if strings.Contains(value, ".") {
+				real = "1"
+			}
+			params.Set("sRealFlag", real)
+		}
+
+		_, err := t.get("/KAREL/ComSet?" + params.Encode())
+		return err
+	case *fanuc.FileClient:
+		return t.edit(MDFile(typ), func(src string) (string, error) {
+			return ReplaceValue(typ, src, id, value)
+		})
 	}
+
+	return fmt.Errorf("cannot set values on %q", t.Name)
+}
+
+// edit applies fn to the pending contents of a backup file
+func (t *Target) edit(filename string, fn func(src string) (string, error)) error {
+	src, ok := t.edits[filename]
+	if !ok {
+		var err error
+		src, err = t.readMD(filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	src, err := fn(src)
+	if err != nil {
+		return err
+	}
+
+	if t.edits == nil {
+		t.edits = make(map[string]string)
+	}
+	t.edits[filename] = src
+
+	return nil
+}
+
+// Save writes any pending comment changes to the target's backup
+// directory. It is a noop for remote hosts.
+func (t *Target) Save() error {
+	if len(t.edits) == 0 {
+		return nil
+	}
+
+	for filename, src := range t.edits {
+		path := filepath.Join(t.Name, filename)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(path, []byte(src), info.Mode())
+		if err != nil {
+			return err
+		}
+
+		delete(t.edits, filename)
+	}
+
+	// the client caches IO, so start fresh
+	client, err := fanuc.NewFileClient(t.Name)
+	if err != nil {
+		return err
+	}
+	t.client = client
+
+	return nil
 }
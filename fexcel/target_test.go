@@ -1,6 +1,8 @@
 package fexcel
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	fanuc "github.com/onerobotics/go-fanuc"
@@ -63,3 +65,78 @@ func TestGetComments(t *testing.T) {
 		t.Errorf("numregs not found")
 	}
 }
+
+func TestGetCommentsStringRegistersAndUserAlarms(t *testing.T) {
+	target, err := NewTarget("testdata", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		typ     Type
+		count   int
+		id      int
+		comment string
+	}{
+		{Sreg, 25, 1, "sreg1"},
+		{Sreg, 25, 3, "RecipeName"},
+		{Sreg, 25, 4, ""},
+		{Ualm, 10, 2, "test two"},
+		{Ualm, 10, 5, ""},
+	}
+
+	for _, test := range tests {
+		err = target.GetComments(test.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if count := len(target.Comments[test.typ]); count != test.count {
+			t.Errorf("Got %d %ss. Want %d", count, test.typ, test.count)
+		}
+
+		if c, ok := target.Comments[test.typ][test.id]; !ok {
+			t.Errorf("%s[%d] undefined", test.typ, test.id)
+		} else if c != test.comment {
+			t.Errorf("Bad comment for %s[%d]. Got %q, want %q", test.typ, test.id, c, test.comment)
+		}
+	}
+}
+
+func TestGetCommentsHTTP(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/MD/strreg.va":
+			rw.Write([]byte(" [1] = 'abc'  'RecipeName' \n [2] = Uninitialized  '' \n"))
+		case "/MD/sysvars.va":
+			rw.Write([]byte("[*SYSTEM*]$UALRM_MSG  Storage: SHADOW  Access: RW  : ARRAY[2] OF STRING[29]\n  [1] = 'Gripper fault'\n  [2] = Uninitialized\n"))
+		default:
+			http.Error(rw, "Not implemented", http.StatusNotImplemented)
+		}
+	}))
+	defer s.Close()
+
+	target, err := NewTarget(s.URL, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = target.GetComments(Sreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := target.Comments[Sreg][1]; got != "RecipeName" {
+		t.Errorf("Bad comment for SR[1]. Got %q, want %q", got, "RecipeName")
+	}
+	if len(target.Comments[Sreg]) != 2 {
+		t.Errorf("Got %d SRs. Want 2", len(target.Comments[Sreg]))
+	}
+
+	err = target.GetComments(Ualm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := target.Comments[Ualm][1]; got != "Gripper fault" {
+		t.Errorf("Bad comment for UALM[1]. Got %q, want %q", got, "Gripper fault")
+	}
+}
@@ -1,6 +1,9 @@
 package fexcel
 
-import "strconv"
+import (
+	"fmt"
+	"strconv"
+)
 
 type Type int
 
@@ -46,6 +49,9 @@ var types = [...]string{
 	Sout:     "SO",
 }
 
+// types that have comments on the controller
+var commentTypes = []Type{Numreg, Posreg, Ualm, Ain, Aout, Din, Dout, Gin, Gout, Rin, Rout, Sreg, Flag}
+
 func (t Type) String() string {
 	s := ""
 	if 0 <= t && t < Type(len(types)) {
@@ -56,3 +62,26 @@ func (t Type) String() string {
 	}
 	return s
 }
+
+// MarshalText encodes a Type as its name, e.g. "R" or "DI".
+func (t Type) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *Type) UnmarshalText(text []byte) error {
+	for i, name := range types {
+		if name == string(text) {
+			*t = Type(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown type %q", text)
+}
+
+// ParseType returns the Type with the provided name, e.g. "R" or "DI".
+func ParseType(s string) (Type, error) {
+	var t Type
+	err := t.UnmarshalText([]byte(s))
+	return t, err
+}
@@ -0,0 +1,420 @@
+package fexcel
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// go-fanuc does not expose string registers or user alarms, and it
+// can only read backups, so we handle the relevant .va data ourselves.
+
+var (
+	numregsRegexp = regexp.MustCompile(`\[(\d+)\] = (-?\d*(\.\d+)?)  '([^']*)'`)
+	posregsRegexp = regexp.MustCompile(`\[(\d+),(\d+)\] =   '([^']*)'`)
+	sregsRegexp   = regexp.MustCompile(`\s+\[(\d+)\] = ('[^']*'|Uninitialized)  '([^']*)'`)
+	ualmsRegexp   = regexp.MustCompile(`\s+\[(\d+)\] = ('([^']*)'|Uninitialized)`)
+)
+
+const ualmMsgHeader = "$UALRM_MSG"
+
+// iostate.dg prefixes
+var ioPrefix = [...]string{
+	Ain:  "AIN",
+	Aout: "AOUT",
+	Din:  "DIN",
+	Dout: "DOUT",
+	Gin:  "GIN",
+	Gout: "GOUT",
+	Rin:  "RI",
+	Rout: "RO",
+	Flag: "FLG",
+}
+
+// IO comments are fixed-width when ports are listed in columns
+const ioCommentWidth = 24
+
+// MDFile returns the name of the MD: file that holds comments for the
+// provided type
+func MDFile(t Type) string {
+	switch t {
+	case Numreg:
+		return "numreg.va"
+	case Posreg:
+		return "posreg.va"
+	case Sreg:
+		return "strreg.va"
+	case Ualm:
+		return "sysvars.va"
+	case Ain, Aout, Din, Dout, Flag, Gin, Gout, Rin, Rout:
+		return "iostate.dg"
+	}
+
+	return ""
+}
+
+type stringRegister struct {
+	Id      int
+	Comment string
+	Value   string
+}
+
+type positionRegister struct {
+	Id       int
+	Group    int
+	Comment  string
+	Position *Position // nil when uninitialized
+	Err      error     // when the position could not be parsed
+}
+
+type userAlarm struct {
+	Id      int
+	Comment string
+}
+
+// parses the contents of strreg.va
+func parseStringRegisters(src string) (sregs []stringRegister, err error) {
+	matches := sregsRegexp.FindAllStringSubmatch(src, -1)
+	for _, m := range matches {
+		id, err := strconv.Atoi(m[1])
+		if err != nil {
+			return sregs, err
+		}
+
+		value := strings.Trim(m[2], "'")
+		if m[2] == "Uninitialized" {
+			value = ""
+		}
+
+		sregs = append(sregs, stringRegister{Id: id, Value: value, Comment: m[3]})
+	}
+
+	return
+}
+
+var (
+	cartesianRegexp = regexp.MustCompile(`Config:\s*([^\r\n]*?)\s*\r?\n\s*X:\s*(\S+)\s+Y:\s*(\S+)\s+Z:\s*(\S+)\s*\r?\n\s*W:\s*(\S+)\s+P:\s*(\S+)\s+R:\s*(\S+)`)
+	jointRegexp     = regexp.MustCompile(`J(\d+) =\s*(\S+) deg`)
+)
+
+// parses the contents of posreg.va. go-fanuc skips positions with
+// negative turn counts or extended axes, so we parse them ourselves.
+// A position that cannot be parsed doesn't stop the rest from being
+// read; its register's Err is set instead.
+func parsePositionRegisters(src string) (posregs []positionRegister, err error) {
+	for _, e := range positionEntries(src) {
+		m := e.match
+		group, err := strconv.Atoi(src[m[2]:m[3]])
+		if err != nil {
+			return posregs, err
+		}
+		id, err := strconv.Atoi(src[m[4]:m[5]])
+		if err != nil {
+			return posregs, err
+		}
+
+		r := positionRegister{Id: id, Group: group, Comment: src[m[6]:m[7]]}
+		r.Position, err = parsePositionBody(src[m[1]:e.end])
+		if err != nil {
+			r.Position, r.Err = nil, fmt.Errorf("PR[%d,%d]: %s", group, id, err)
+		}
+
+		posregs = append(posregs, r)
+	}
+
+	return
+}
+
+type positionEntry struct {
+	match []int // of posregsRegexp
+	end   int   // of the position that follows the comment
+}
+
+// positionEntries returns the position registers of posreg.va. Each
+// position runs from the end of its comment to the end of its last
+// line, including the line ending.
+func positionEntries(src string) []positionEntry {
+	matches := posregsRegexp.FindAllStringSubmatchIndex(src, -1)
+
+	var entries []positionEntry
+	for i, m := range matches {
+		end := len(src)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		if j := strings.Index(src[m[1]:end], "\n[*"); j >= 0 {
+			end = m[1] + j
+		}
+
+		end = m[1] + len(strings.TrimRight(src[m[1]:end], " \r\n"))
+		if j := strings.Index(src[end:], "\n"); j >= 0 {
+			end += j + 1
+		} else {
+			end = len(src)
+		}
+
+		entries = append(entries, positionEntry{match: m, end: end})
+	}
+
+	return entries
+}
+
+// parsePositionBody parses what follows the comment of a position
+// register
+func parsePositionBody(body string) (*Position, error) {
+	if strings.HasPrefix(strings.TrimSpace(body), uninitialized) {
+		return nil, nil
+	}
+
+	p := Position{}
+	if m := cartesianRegexp.FindStringSubmatch(body); m != nil {
+		p.Config = m[1]
+		for _, v := range m[2:] {
+			a, err := parseAxis(v)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", v)
+			}
+			p.Axes = append(p.Axes, a)
+		}
+	} else if ms := jointRegexp.FindAllStringSubmatch(body, -1); ms != nil {
+		p.Joint = true
+		for _, m := range ms {
+			a, err := parseAxis(m[2])
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", m[2])
+			}
+			p.Axes = append(p.Axes, a)
+		}
+	} else {
+		return nil, fmt.Errorf("unknown position %q", strings.TrimSpace(body))
+	}
+
+	return &p, p.validate()
+}
+
+// positionBody formats a position the way posreg.va lists it after the
+// comment, with lines ending in nl
+func positionBody(p *Position, nl string) string {
+	if p == nil {
+		return " " + uninitialized + nl
+	}
+
+	var b strings.Builder
+	if p.Joint {
+		b.WriteString("   Group: 1" + nl)
+		for i, a := range p.Axes {
+			fmt.Fprintf(&b, "  J%d = %9s deg ", i+1, formatAxis(a))
+			if i%3 == 2 || i == len(p.Axes)-1 {
+				b.WriteString(nl)
+			}
+		}
+		return b.String()
+	}
+
+	b.WriteString(" " + nl)
+	b.WriteString("  Group: 1   Config: " + p.Config + nl)
+	for i, a := range p.Axes {
+		fmt.Fprintf(&b, "  %s:%10s", cartesianAxes[i], formatAxis(a))
+		if i%3 == 2 {
+			b.WriteString(nl)
+		} else {
+			b.WriteString(" ")
+		}
+	}
+	return b.String()
+}
+
+// returns the bounds of the portion of a .va file that belongs to the
+// provided variable, e.g. $UALRM_MSG from sysvars.va
+func varSectionIndex(src string, name string) (start, end int, err error) {
+	start = strings.Index(src, "]"+name+" ")
+	if start < 0 {
+		return 0, 0, fmt.Errorf("variable %s not found", name)
+	}
+
+	end = len(src)
+	if i := strings.Index(src[start:], "\n[*"); i >= 0 {
+		end = start + i
+	}
+
+	return start, end, nil
+}
+
+func varSection(src string, name string) (string, error) {
+	start, end, err := varSectionIndex(src, name)
+	if err != nil {
+		return "", err
+	}
+
+	return src[start:end], nil
+}
+
+// parses the user alarm messages ($UALRM_MSG) out of sysvars.va
+func parseUserAlarms(src string) (ualms []userAlarm, err error) {
+	section, err := varSection(src, ualmMsgHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := ualmsRegexp.FindAllStringSubmatch(section, -1)
+	for _, m := range matches {
+		id, err := strconv.Atoi(m[1])
+		if err != nil {
+			return ualms, err
+		}
+
+		ualms = append(ualms, userAlarm{Id: id, Comment: m[3]})
+	}
+
+	return
+}
+
+// ReplaceComment returns a copy of src (the contents of MDFile(t)) with the
+// comment for t[id] replaced. Everything else is left untouched.
+func ReplaceComment(t Type, src string, id int, comment string) (string, error) {
+	var (
+		re           *regexp.Regexp
+		idGroup      int
+		commentGroup int
+		quote        string // added around the comment
+		io           bool
+		start, end   = 0, len(src)
+	)
+
+	switch t {
+	case Numreg:
+		re, idGroup, commentGroup = numregsRegexp, 1, 4
+	case Posreg:
+		re, idGroup, commentGroup = posregsRegexp, 2, 3
+	case Sreg:
+		re, idGroup, commentGroup = sregsRegexp, 1, 3
+	case Ualm:
+		var err error
+		start, end, err = varSectionIndex(src, ualmMsgHeader)
+		if err != nil {
+			return "", err
+		}
+		// the match includes the quotes so we can replace Uninitialized
+		re, idGroup, commentGroup, quote = ualmsRegexp, 1, 2, "'"
+	case Ain, Aout, Din, Dout, Flag, Gin, Gout, Rin, Rout:
+		re = regexp.MustCompile(`\b` + ioPrefix[t] + `\[\s*(\d+)\]\s+(ON|OFF|\d+)  ([^\r\n]{0,24})`)
+		idGroup, commentGroup, io = 1, 3, true
+	default:
+		return "", fmt.Errorf("cannot set comment for %s", t)
+	}
+
+	if !io && strings.Contains(comment, "'") {
+		return "", fmt.Errorf("comment %q for %s[%d] cannot contain a single quote", comment, t, id)
+	}
+
+	src, found, err := replaceGroup(src, start, end, re, idGroup, commentGroup, id, func(to int) string {
+		// keep the columns aligned when another port follows on the same line
+		if io && to < len(src) && src[to] != '\r' && src[to] != '\n' {
+			return fmt.Sprintf("%-*s", ioCommentWidth, comment)
+		}
+		return quote + comment + quote
+	})
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("%s[%d] not found in %s", t, id, MDFile(t))
+	}
+
+	return src, nil
+}
+
+// ReplaceValue returns a copy of src (the contents of MDFile(t)) with the
+// value of t[id] replaced. value must already be formatted by FormatValue.
+func ReplaceValue(t Type, src string, id int, value string) (string, error) {
+	var (
+		re         *regexp.Regexp
+		valueGroup int
+		quote      string
+	)
+
+	switch t {
+	case Numreg:
+		if value == "" {
+			return "", fmt.Errorf("value for %s[%d] cannot be blank", t, id)
+		}
+		re, valueGroup = numregsRegexp, 2
+	case Sreg:
+		if strings.Contains(value, "'") {
+			return "", fmt.Errorf("value %q for %s[%d] cannot contain a single quote", value, t, id)
+		}
+		// the match includes the quotes so we can replace Uninitialized
+		re, valueGroup, quote = sregsRegexp, 2, "'"
+	case Posreg:
+		p, err := ParsePosition(value)
+		if err != nil {
+			return "", err
+		}
+		return ReplacePosition(src, id, p)
+	default:
+		return "", fmt.Errorf("cannot set value for %s", t)
+	}
+
+	src, found, err := replaceGroup(src, 0, len(src), re, 1, valueGroup, id, func(int) string {
+		return quote + value + quote
+	})
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("%s[%d] not found in %s", t, id, MDFile(t))
+	}
+
+	return src, nil
+}
+
+// ReplacePosition returns a copy of src (the contents of posreg.va) with
+// the group 1 position of PR[id] replaced. A nil position is
+// Uninitialized.
+func ReplacePosition(src string, id int, p *Position) (string, error) {
+	for _, e := range positionEntries(src) {
+		m := e.match
+		if src[m[2]:m[3]] != "1" || src[m[4]:m[5]] != strconv.Itoa(id) {
+			continue
+		}
+
+		nl := "\n"
+		if strings.Contains(src[m[1]:e.end], "\r\n") {
+			nl = "\r\n"
+		}
+
+		return src[:m[1]] + positionBody(p, nl) + src[e.end:], nil
+	}
+
+	return "", fmt.Errorf("%s[%d] not found in %s", Posreg, id, MDFile(Posreg))
+}
+
+// replaceGroup replaces the group of every match of re in src[start:end]
+// whose idGroup is id with the result of field, which is given the end
+// of the replaced group. It reports whether a match was found.
+func replaceGroup(src string, start, end int, re *regexp.Regexp, idGroup, group, id int, field func(to int) string) (string, bool, error) {
+	var b strings.Builder
+	found := false
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(src[start:end], -1) {
+		i, err := strconv.Atoi(src[start+m[2*idGroup] : start+m[2*idGroup+1]])
+		if err != nil {
+			return "", false, err
+		}
+		if i != id {
+			continue
+		}
+
+		from, to := start+m[2*group], start+m[2*group+1]
+
+		b.WriteString(src[last:from])
+		b.WriteString(field(to))
+		last = to
+		found = true
+	}
+
+	b.WriteString(src[last:])
+
+	return b.String(), found, nil
+}
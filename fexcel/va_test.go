@@ -0,0 +1,109 @@
+package fexcel
+
+import (
+	"testing"
+)
+
+func TestSetComment(t *testing.T) {
+	tests := []struct {
+		typ     Type
+		src     string
+		id      int
+		comment string
+		want    string
+	}{
+		{Numreg, "  [1] = 0  'one' \n  [2] = 70.000000  'two' \n", 2, "deux", "  [1] = 0  'one' \n  [2] = 70.000000  'deux' \n"},
+		{Posreg, "    [1,1] =   '' Uninitialized\n    [2,1] =   '' Uninitialized\n", 1, "home", "    [1,1] =   'home' Uninitialized\n    [2,1] =   'home' Uninitialized\n"},
+		{Sreg, "  [1] = Uninitialized  '' \r\n", 1, "recipe", "  [1] = Uninitialized  'recipe' \r\n"},
+		{Ualm, "[*SYSTEM*]$UALRM_MSG  Storage: SHADOW\n  [1] = Uninitialized\n[*SYSTEM*]$UALRM_SEV  Storage: SHADOW\n  [1] = 6\n", 1, "fault", "[*SYSTEM*]$UALRM_MSG  Storage: SHADOW\n  [1] = 'fault'\n[*SYSTEM*]$UALRM_SEV  Storage: SHADOW\n  [1] = 6\n"},
+		{Din, "DIN[   1] OFF  \nDIN[  10]  ON  old\n", 10, "new", "DIN[   1] OFF  \nDIN[  10]  ON  new\n"},
+		{Din, "DIN[   1] OFF                            DIN[   2] OFF  \n", 1, "x", "DIN[   1] OFF  x                         DIN[   2] OFF  \n"},
+	}
+
+	for _, test := range tests {
+		got, err := ReplaceComment(test.typ, test.src, test.id, test.comment)
+		if err != nil {
+			t.Errorf("ReplaceComment(%s[%d]): %s", test.typ, test.id, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("ReplaceComment(%s[%d]): Got %q, want %q", test.typ, test.id, got, test.want)
+		}
+	}
+}
+
+func TestSetCommentErrors(t *testing.T) {
+	tests := []struct {
+		typ     Type
+		src     string
+		id      int
+		comment string
+		err     string
+	}{
+		{Numreg, "  [1] = 0  'one' \n", 2, "two", "R[2] not found in numreg.va"},
+		{Numreg, "  [1] = 0  'one' \n", 1, "it's", "comment \"it's\" for R[1] cannot contain a single quote"},
+		{Ualm, "  [1] = 'one'\n", 1, "two", "variable $UALRM_MSG not found"},
+		{Constant, "", 1, "foo", "cannot set comment for Constant"},
+	}
+
+	for _, test := range tests {
+		_, err := ReplaceComment(test.typ, test.src, test.id, test.comment)
+		if err == nil {
+			t.Errorf("ReplaceComment(%s[%d]): expected an error", test.typ, test.id)
+			continue
+		}
+
+		if err.Error() != test.err {
+			t.Errorf("bad error. Got %q, want %q", err.Error(), test.err)
+		}
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	tests := []struct {
+		typ   Type
+		src   string
+		id    int
+		value string
+		want  string
+	}{
+		{Numreg, "  [1] = 0  'one' \n  [2] = 70.000000  'two' \n", 2, "-1.500000", "  [1] = 0  'one' \n  [2] = -1.500000  'two' \n"},
+		{Numreg, "  [1] = 0  'one' \n", 1, "12", "  [1] = 12  'one' \n"},
+		{Sreg, "  [1] = Uninitialized  '' \r\n", 1, "recipe", "  [1] = 'recipe'  '' \r\n"},
+		{Sreg, "  [1] = 'old'  'name' \n", 1, "", "  [1] = ''  'name' \n"},
+	}
+
+	for _, test := range tests {
+		got, err := ReplaceValue(test.typ, test.src, test.id, test.value)
+		if err != nil {
+			t.Errorf("ReplaceValue(%s[%d]): %s", test.typ, test.id, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("ReplaceValue(%s[%d]): Got %q, want %q", test.typ, test.id, got, test.want)
+		}
+	}
+
+	errors := []struct {
+		typ   Type
+		src   string
+		id    int
+		value string
+		err   string
+	}{
+		{Numreg, "  [1] = 0  'one' \n", 2, "1", "R[2] not found in numreg.va"},
+		{Numreg, "  [1] = 0  'one' \n", 1, "", "value for R[1] cannot be blank"},
+		{Sreg, "  [1] = ''  'one' \n", 1, "it's", "value \"it's\" for SR[1] cannot contain a single quote"},
+		{Ualm, "", 1, "1", "cannot set value for UALM"},
+		{Posreg, "", 1, "1", "invalid position \"1\""},
+	}
+
+	for _, test := range errors {
+		_, err := ReplaceValue(test.typ, test.src, test.id, test.value)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("ReplaceValue(%s[%d]): Got error %v, want %q", test.typ, test.id, err, test.err)
+		}
+	}
+}
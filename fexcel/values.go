@@ -0,0 +1,58 @@
+package fexcel
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HasValues reports whether definitions of t can have a value
+func HasValues(t Type) bool {
+	return t == Numreg || t == Sreg || t == Posreg
+}
+
+var integerRegexp = regexp.MustCompile(`^[+-]?\d+$`)
+
+// FormatValue returns a value the way the controller stores it. Numeric
+// register values are either 32-bit integers (e.g. 70) or 32-bit reals
+// with six decimal places (e.g. 70.000000). String register values are
+// left alone, and positions are formatted by Position.String. A blank
+// value stays blank.
+func FormatValue(t Type, value string) (string, error) {
+	switch t {
+	case Numreg:
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return "", nil
+		}
+
+		if integerRegexp.MatchString(value) {
+			i, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return "", fmt.Errorf("%s value %q is out of range", t, value)
+			}
+			return strconv.FormatInt(i, 10), nil
+		}
+
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return "", fmt.Errorf("%s value %q is not a number", t, value)
+		}
+		return strconv.FormatFloat(f, 'f', 6, 32), nil
+	case Sreg:
+		return value, nil
+	case Posreg:
+		if strings.TrimSpace(value) == "" {
+			return "", nil
+		}
+
+		p, err := ParsePosition(value)
+		if err != nil {
+			return "", err
+		}
+		return p.String(), nil
+	}
+
+	return "", fmt.Errorf("%ss do not have values", t)
+}
@@ -0,0 +1,224 @@
+package fexcel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		typ   Type
+		value string
+		want  string
+	}{
+		{Numreg, "", ""},
+		{Numreg, " 70 ", "70"},
+		{Numreg, "+5", "5"},
+		{Numreg, "-12", "-12"},
+		{Numreg, "70.0", "70.000000"},
+		{Numreg, "1.5", "1.500000"},
+		{Numreg, "0.1234567", "0.123457"},
+		{Numreg, "1e3", "1000.000000"},
+		{Sreg, " recipe A ", " recipe A "},
+	}
+
+	for _, test := range tests {
+		got, err := FormatValue(test.typ, test.value)
+		if err != nil {
+			t.Errorf("FormatValue(%s, %q): %s", test.typ, test.value, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("FormatValue(%s, %q): Got %q, want %q", test.typ, test.value, got, test.want)
+		}
+	}
+
+	errors := []struct {
+		typ   Type
+		value string
+		err   string
+	}{
+		{Numreg, "abc", "R value \"abc\" is not a number"},
+		{Numreg, "3000000000", "R value \"3000000000\" is out of range"},
+		{Ualm, "1", "UALMs do not have values"},
+		{Posreg, "X=1", "position \"X=1\" needs a CONFIG or joint angles"},
+	}
+
+	for _, test := range errors {
+		_, err := FormatValue(test.typ, test.value)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("FormatValue(%s, %q): Got error %v, want %q", test.typ, test.value, err, test.err)
+		}
+	}
+}
+
+// valuesCSV holds comments that match testdata and a few values that don't
+const valuesCSV = `R,Comment,Value,,SR,Comment,Value
+2,two,0,,1,sreg1,hello
+3,three,1.5,,3,RecipeName,recipe B
+4,four,,,,,
+5,five,70,,,,
+`
+
+var valuesConfig = Config{FileConfig: FileConfig{
+	Sheet:       "regs",
+	Offset:      1,
+	ValueOffset: 2,
+	Numregs:     []string{"A2"},
+	Sregs:       []string{"E2"},
+}}
+
+func TestReadValues(t *testing.T) {
+	dir := tempCSV(t, map[string]string{"regs.csv": valuesCSV + "6,six,abc,,,,\n"})
+	defer os.RemoveAll(dir)
+
+	f, err := OpenFile(filepath.Join(dir, "regs.csv"), valuesConfig.FileConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.Definitions(Numreg)
+	if err == nil || !strings.Contains(err.Error(), "R value \"abc\" is not a number") {
+		t.Errorf("Expected a bad value error. Got %v", err)
+	}
+
+	defs, err := f.Definitions(Sreg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Definition{{Sreg, 1, "sreg1", "hello"}, {Sreg, 3, "RecipeName", "recipe B"}}
+	if len(defs) != len(want) || defs[0] != want[0] || defs[1] != want[1] {
+		t.Errorf("Got %v, want %v", defs, want)
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	dir := tempCSV(t, map[string]string{"regs.csv": valuesCSV})
+	defer os.RemoveAll(dir)
+
+	d, err := NewDiffCommand(filepath.Join(dir, "regs.csv"), valuesConfig, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := d.CompareAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		typ         Type
+		values      bool
+		comparisons int
+		differences int
+	}{
+		{Numreg, false, 4, 0},
+		{Numreg, true, 3, 2},
+		{Sreg, false, 2, 0},
+		{Sreg, true, 2, 1},
+	}
+
+	if len(results) != len(want) {
+		t.Fatalf("Got %d results, want %d", len(results), len(want))
+	}
+
+	for i, w := range want {
+		r := results[i]
+		if r.Type != w.typ || r.Values != w.values || len(r.Comparisons) != w.comparisons || r.Differences() != w.differences {
+			t.Errorf("results[%d]: Got %s (values %t) with %d differences in %d comparisons, want %s (values %t) with %d in %d",
+				i, r.Type, r.Values, r.Differences(), len(r.Comparisons), w.typ, w.values, w.differences, w.comparisons)
+		}
+	}
+
+	if c := results[1].Comparisons[1]; c.Id != 3 || c.Want != "1.500000" || c.Got[0] != "0" {
+		t.Errorf("Bad R[3] value comparison: %v", c)
+	}
+}
+
+func TestSetValues(t *testing.T) {
+	dir := tempBackup(t)
+	defer os.RemoveAll(dir)
+
+	csvDir := tempCSV(t, map[string]string{"regs.csv": valuesCSV})
+	defer os.RemoveAll(csvDir)
+	fpath := filepath.Join(csvDir, "regs.csv")
+
+	s, err := NewSetCommand(fpath, valuesConfig, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Values = true
+	s.SnapshotDir = filepath.Join(csvDir, "snapshots")
+
+	result, err := s.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Counts[dir][Numreg] != 2 || result.Counts[dir][Sreg] != 1 {
+		t.Errorf("Got counts %v, want 2 Rs and 1 SR", result.Counts[dir])
+	}
+
+	files := map[string][]string{
+		"numreg.va": {"  [3] = 1.500000  'three' \n", "  [4] = 0  'four' \n", "  [5] = 70  'five' \n"},
+		"strreg.va": {"  [1] = 'hello'  'sreg1' \n", "  [3] = 'recipe B'  'RecipeName' \n"},
+	}
+	for filename, lines := range files {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, line := range lines {
+			if !strings.Contains(string(b), line) {
+				t.Errorf("%s does not contain %q", filename, line)
+			}
+		}
+	}
+
+	r, err := NewRollbackCommand(s.SnapshotPath, Config{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := r.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("Restored %d values, want 3", count)
+	}
+
+	for filename := range files {
+		orig, err := ioutil.ReadFile(filepath.Join("testdata", filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != string(orig) {
+			t.Errorf("%s was not restored", filename)
+		}
+	}
+
+	// values need a column to come from
+	cfg := valuesConfig
+	cfg.FileConfig.ValueOffset = 0
+	s, err = NewSetCommand(fpath, cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Values = true
+
+	_, err = s.Plan()
+	if err == nil || !strings.Contains(err.Error(), "cannot set values without a value column") {
+		t.Errorf("Expected a missing value column error. Got %v", err)
+	}
+}
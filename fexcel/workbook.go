@@ -0,0 +1,234 @@
+package fexcel
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+// A Workbook stores the cells of a File. *excelize.File is a
+// Workbook.
+type Workbook interface {
+	GetCellValue(sheet, axis string) (string, error)
+	SetCellValue(sheet, axis string, value interface{}) error
+	GetRows(sheet string) ([][]string, error)
+	NewSheet(name string) int
+	SaveAs(path string) error
+}
+
+// isDelimited reports whether path is a CSV or TSV file
+func isDelimited(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".tsv":
+		return true
+	}
+	return false
+}
+
+func openWorkbook(path string, defaultSheet string) (Workbook, error) {
+	if isDelimited(path) {
+		return openCSVWorkbook(path, defaultSheet)
+	}
+
+	return excelize.OpenFile(path)
+}
+
+func newWorkbook(path string, defaultSheet string) Workbook {
+	if isDelimited(path) {
+		return newCSVWorkbook(path, defaultSheet)
+	}
+
+	return excelize.NewFile()
+}
+
+// excel returns the excelize file behind f, if there is one
+func (f *File) excel() (*excelize.File, bool) {
+	x, ok := f.book.(*excelize.File)
+	return x, ok
+}
+
+// cellValue returns the value at axis, or "" if the records don't reach
+// that far
+func cellValue(records [][]string, axis string) (string, error) {
+	col, row, err := excelize.CellNameToCoordinates(axis)
+	if err != nil {
+		return "", err
+	}
+
+	if row > len(records) || col > len(records[row-1]) {
+		return "", nil
+	}
+
+	return records[row-1][col-1], nil
+}
+
+// setCell sets the value at axis, growing the records as needed
+func setCell(records [][]string, axis string, value interface{}) ([][]string, error) {
+	col, row, err := excelize.CellNameToCoordinates(axis)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(records) < row {
+		records = append(records, nil)
+	}
+	for len(records[row-1]) < col {
+		records[row-1] = append(records[row-1], "")
+	}
+	records[row-1][col-1] = fmt.Sprint(value)
+
+	return records, nil
+}
+
+// A csvWorkbook is a directory of CSV (or TSV) files. The file that was
+// opened holds the default sheet, and every other sheet is stored in a
+// file of the same name next to it, e.g. sheet IO is IO.csv.
+type csvWorkbook struct {
+	path         string
+	defaultSheet string
+	comma        rune
+
+	sheets map[string][][]string
+}
+
+func newCSVWorkbook(path string, defaultSheet string) *csvWorkbook {
+	w := csvWorkbook{
+		path:         path,
+		defaultSheet: defaultSheet,
+		comma:        ',',
+		sheets:       make(map[string][][]string),
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		w.comma = '\t'
+	}
+
+	return &w
+}
+
+func openCSVWorkbook(path string, defaultSheet string) (*csvWorkbook, error) {
+	w := newCSVWorkbook(path, defaultSheet)
+
+	// fail early if the file doesn't exist. Other sheets are read as
+	// needed.
+	_, err := w.sheet(defaultSheet)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// sheetPath returns the file a sheet is stored in
+func (w *csvWorkbook) sheetPath(sheet string) string {
+	if sheet == w.defaultSheet {
+		return w.path
+	}
+
+	return filepath.Join(filepath.Dir(w.path), sheet+filepath.Ext(w.path))
+}
+
+// key returns the name a sheet is stored under. A sheet named after
+// the opened file is the default sheet.
+func (w *csvWorkbook) key(sheet string) string {
+	if filepath.Clean(w.sheetPath(sheet)) == filepath.Clean(w.path) {
+		return w.defaultSheet
+	}
+	return sheet
+}
+
+func (w *csvWorkbook) sheet(name string) ([][]string, error) {
+	name = w.key(name)
+	if records, ok := w.sheets[name]; ok {
+		return records, nil
+	}
+
+	b, err := ioutil.ReadFile(w.sheetPath(name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("sheet %s is not exist (no %s)", name, w.sheetPath(name))
+	} else if err != nil {
+		return nil, err
+	}
+
+	// spreadsheet exports often start with a byte order mark
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comma = w.comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", w.sheetPath(name), err)
+	}
+
+	w.sheets[name] = records
+	return records, nil
+}
+
+func (w *csvWorkbook) GetCellValue(sheet, axis string) (string, error) {
+	records, err := w.sheet(sheet)
+	if err != nil {
+		return "", err
+	}
+
+	return cellValue(records, axis)
+}
+
+func (w *csvWorkbook) SetCellValue(sheet, axis string, value interface{}) error {
+	records, err := w.sheet(sheet)
+	if err != nil {
+		return err
+	}
+
+	w.sheets[w.key(sheet)], err = setCell(records, axis, value)
+	return err
+}
+
+func (w *csvWorkbook) GetRows(sheet string) ([][]string, error) {
+	return w.sheet(sheet)
+}
+
+// NewSheet adds an empty sheet if its file doesn't exist yet. A file
+// that exists but cannot be read is left alone so the next read or
+// write of the sheet returns the error instead of SaveAs replacing it.
+// The return value is only there to match excelize.
+func (w *csvWorkbook) NewSheet(name string) int {
+	if _, ok := w.sheets[w.key(name)]; !ok {
+		if _, err := os.Stat(w.sheetPath(name)); os.IsNotExist(err) {
+			w.sheets[w.key(name)] = nil
+		}
+	}
+	return len(w.sheets)
+}
+
+// SaveAs writes every sheet that has been read. The default sheet is
+// written to path, and the others next to it.
+func (w *csvWorkbook) SaveAs(path string) error {
+	w.path = path
+
+	for name, records := range w.sheets {
+		var b bytes.Buffer
+		cw := csv.NewWriter(&b)
+		cw.Comma = w.comma
+
+		err := cw.WriteAll(records)
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(w.sheetPath(name), b.Bytes(), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package fexcel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tempCSV(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "fexcel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, contents := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestCSVDefinitions(t *testing.T) {
+	dir := tempCSV(t, map[string]string{
+		"data.csv": "\xef\xbb\xbfnumregs,comment\n1,this is an extremely long comment\n2,two\n3,\"three, quoted\"\n",
+		"IO.csv":   "di,comment\n101-102,sensor %d\n",
+	})
+	defer os.RemoveAll(dir)
+
+	cfg := FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"Data:[numregs]"}, Dins: []string{"IO:A2"}}
+	f, err := OpenFile(filepath.Join(dir, "data.csv"), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := f.AllDefinitions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[Type][]Definition{
+		Numreg: {{Numreg, 1, "this is an extremely long comment", ""}, {Numreg, 2, "two", ""}, {Numreg, 3, "three, quoted", ""}},
+		Din:    {{Din, 101, "sensor 101", ""}, {Din, 102, "sensor 102", ""}},
+	}
+	for typ, want := range expected {
+		if len(defs[typ]) != len(want) {
+			t.Fatalf("Bad %s length. Got %d, want %d", typ, len(defs[typ]), len(want))
+		}
+		for i, d := range defs[typ] {
+			if d != want[i] {
+				t.Errorf("Bad definition. Got %v, want %v", d, want[i])
+			}
+		}
+	}
+}
+
+func TestCSVSave(t *testing.T) {
+	dir := tempCSV(t, map[string]string{
+		"io.tsv": "1\tone\n2\ttwo\n",
+	})
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "io.tsv")
+	cfg := FileConfig{Sheet: "Sheet1", Offset: 1, Dins: []string{"A1"}}
+	f, err := OpenFile(fpath, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.SetValue("Sheet1", 2, 2, "TWO")
+	f.SetValue("Sheet1", 1, 3, 3)
+	f.SetValue("Sheet1", 2, 3, "three")
+	err = f.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1\tone\n2\tTWO\n3\tthree\n"; string(b) != want {
+		t.Errorf("Bad file. Got %q, want %q", b, want)
+	}
+
+	// a sheet named after the file is the file itself
+	f, err = OpenFile(fpath, FileConfig{Sheet: "Sheet1", Offset: 1, Dins: []string{"io:A1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := f.Definitions(Din)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 3 || defs[2].Comment != "three" {
+		t.Errorf("Bad definitions: %v", defs)
+	}
+}
+
+func TestCSVErrors(t *testing.T) {
+	dir := tempCSV(t, map[string]string{
+		"data.csv": "1,one\n",
+	})
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "data.csv")
+
+	tests := []struct {
+		cfg  FileConfig
+		want string
+	}{
+		{FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"IO:A1"}}, "sheet IO is not exist"},
+		{FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"NUMREGS"}}, "defined names are only supported in .xlsx files"},
+		{FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"Numregs[Id][Comment]"}}, "tables are only supported in .xlsx files"},
+	}
+
+	for _, test := range tests {
+		f, err := OpenFile(fpath, test.cfg)
+		if err == nil {
+			_, err = f.AllDefinitions()
+		}
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Bad error. Got %v, want %q", err, test.want)
+		}
+	}
+
+	_, err := OpenFile(filepath.Join(dir, "missing.csv"), FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A1"}})
+	if err == nil {
+		t.Error("Expected an error opening a missing file")
+	}
+}
+
+func TestCSVCreateSheet(t *testing.T) {
+	dir := tempCSV(t, map[string]string{"data.csv": "1,one\n"})
+	defer os.RemoveAll(dir)
+
+	// a sheet that exists but can't be read
+	err := os.Mkdir(filepath.Join(dir, "IO.csv"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenFile(filepath.Join(dir, "data.csv"), FileConfig{Sheet: "Data", Offset: 1, Numregs: []string{"A1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.CreateSheet("New")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.CreateSheet("IO")
+	if err == nil {
+		t.Error("Expected an error creating an unreadable sheet")
+	}
+
+	// and it isn't replaced
+	err = f.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "IO.csv"))
+	if err != nil || !info.IsDir() {
+		t.Errorf("IO.csv was replaced")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "New.csv")); err != nil {
+		t.Errorf("New sheet was not saved: %s", err)
+	}
+}
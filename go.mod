@@ -10,4 +10,5 @@ require (
 	github.com/onerobotics/go-fanuc v0.6.1
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/viper v1.6.2
+	gopkg.in/yaml.v2 v2.2.4
 )